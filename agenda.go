@@ -0,0 +1,199 @@
+package krools
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// Activation is a rule whose condition is satisfied and which is waiting to be executed.
+type Activation struct {
+	Rule     *RuleHandle
+	Name     string
+	Unit     string
+	Salience int
+
+	// Recency is the number of the last change of facts read by the condition, the bigger it is the more recently
+	// facts were changed.
+	Recency uint64
+	// Specificity is the number of constraints of a pattern condition or the number of fact types read by any other
+	// condition.
+	Specificity int
+	// Sequence is the order in which activations were created within a fire. The activation of an executed rule is
+	// kept while its condition stays satisfied in following cycles, so the sequence tells when the rule became
+	// applicable, not when it was evaluated last time.
+	Sequence uint64
+
+	// fired is set once the rule is executed, so the activation is kept silently if the rule is still applicable.
+	fired bool
+}
+
+// ConflictResolver orders activations of a cycle, rules are executed in the resulting order.
+type ConflictResolver interface {
+	Resolve(activations []*Activation)
+}
+
+type ConflictResolverFn func(activations []*Activation)
+
+func (f ConflictResolverFn) Resolve(activations []*Activation) { f(activations) }
+
+// SalienceResolver orders activations by salience descending, activations with the same salience keep the order
+// rules were added in. It's the default resolver.
+func SalienceResolver() ConflictResolver {
+	return sortingResolver(func(a, b *Activation) int {
+		return cmp.Compare(b.Salience, a.Salience)
+	})
+}
+
+// RecencyResolver puts first activations of rules whose conditions read the most recently changed facts.
+func RecencyResolver() ConflictResolver {
+	return sortingResolver(func(a, b *Activation) int {
+		return cmp.Compare(b.Recency, a.Recency)
+	})
+}
+
+// SpecificityResolver puts first activations of rules with more constraints.
+func SpecificityResolver() ConflictResolver {
+	return sortingResolver(func(a, b *Activation) int {
+		return cmp.Compare(b.Specificity, a.Specificity)
+	})
+}
+
+// FIFOResolver puts first activations that were created earlier, so rules applicable for longer go first.
+func FIFOResolver() ConflictResolver {
+	return sortingResolver(func(a, b *Activation) int {
+		return cmp.Compare(a.Sequence, b.Sequence)
+	})
+}
+
+// LIFOResolver puts first activations that were created later, so rules which became applicable most recently go
+// first.
+func LIFOResolver() ConflictResolver {
+	return sortingResolver(func(a, b *Activation) int {
+		return cmp.Compare(b.Sequence, a.Sequence)
+	})
+}
+
+// RandomResolver shuffles activations, the same seed gives the same order for the same activations. It's safe to share
+// between sessions, but the order then depends on the order sessions use it in.
+func RandomResolver(seed uint64) ConflictResolver {
+	var mu sync.Mutex
+	r := rand.New(rand.NewPCG(seed, seed))
+
+	return ConflictResolverFn(func(activations []*Activation) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		r.Shuffle(len(activations), func(i, j int) {
+			activations[i], activations[j] = activations[j], activations[i]
+		})
+	})
+}
+
+// ChainResolvers orders activations by the first resolver, then activations equal for it by the second one and so
+// on. All resolvers but the last one must keep the order of activations they consider equal, as built-in resolvers
+// do.
+func ChainResolvers(resolvers ...ConflictResolver) ConflictResolver {
+	return ConflictResolverFn(func(activations []*Activation) {
+		for i := len(resolvers) - 1; i >= 0; i-- {
+			resolvers[i].Resolve(activations)
+		}
+	})
+}
+
+func sortingResolver(compare func(a, b *Activation) int) ConflictResolver {
+	return ConflictResolverFn(func(activations []*Activation) {
+		slices.SortStableFunc(activations, compare)
+	})
+}
+
+// agenda keeps activations of a fire and orders them with a resolver.
+type agenda struct {
+	resolver    ConflictResolver
+	listeners   *listeners
+	sequence    uint64
+	activations map[*RuleHandle]*Activation
+}
+
+func newAgenda(resolver ConflictResolver, listeners *listeners) *agenda {
+	return &agenda{
+		resolver:    resolver,
+		listeners:   listeners,
+		activations: make(map[*RuleHandle]*Activation),
+	}
+}
+
+// activate returns the activation of the rule, it creates a new one if the rule has no activation yet. The activation
+// of an executed rule is matched again keeping its sequence.
+func (a *agenda) activate(rule *RuleHandle, recency uint64, specificity int) *Activation {
+	activation, ok := a.activations[rule]
+	if !ok {
+		a.sequence++
+
+		activation = &Activation{
+			Rule:     rule,
+			Name:     rule.name,
+			Unit:     rule.unit,
+			Salience: rule.salience,
+			Sequence: a.sequence,
+		}
+
+		a.activations[rule] = activation
+	}
+
+	activation.Recency = recency
+	activation.Specificity = specificity
+
+	if !ok || activation.fired {
+		activation.fired = false
+		a.listeners.agendaEvent(func(l AgendaEventListener) { l.MatchCreated(MatchEvent{Activation: activation}) })
+	}
+
+	return activation
+}
+
+// fired marks the activation of the executed rule, it's kept until the rule is not applicable anymore.
+func (a *agenda) fired(rule *RuleHandle) {
+	if activation, ok := a.activations[rule]; ok {
+		activation.fired = true
+	}
+}
+
+// cancel drops the activation of the rule, it's reported as cancelled if the rule is not executed.
+func (a *agenda) cancel(rule *RuleHandle) {
+	if activation, ok := a.activations[rule]; ok {
+		delete(a.activations, rule)
+
+		if activation.fired {
+			return
+		}
+
+		a.listeners.agendaEvent(func(l AgendaEventListener) { l.MatchCancelled(MatchEvent{Activation: activation}) })
+	}
+}
+
+// retain drops all activations except passed ones.
+func (a *agenda) retain(activations []*Activation) {
+	retained := make(map[*Activation]struct{}, len(activations))
+	for _, activation := range activations {
+		retained[activation] = struct{}{}
+	}
+
+	for rule, activation := range a.activations {
+		if _, ok := retained[activation]; !ok {
+			a.cancel(rule)
+		}
+	}
+}
+
+func (a *agenda) resolve(activations []*Activation) []*RuleHandle {
+	a.resolver.Resolve(activations)
+
+	rules := make([]*RuleHandle, 0, len(activations))
+	for _, activation := range activations {
+		rules = append(rules, activation.Rule)
+	}
+
+	return rules
+}
@@ -0,0 +1,144 @@
+package krools_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Trigger struct{}
+
+type Older struct{}
+
+type Newer struct{}
+
+func firedOrder(t *testing.T, k *krools.KnowledgeBase, prepare func(s *krools.Session)) []string {
+	t.Helper()
+
+	s := k.NewSession()
+	prepare(s)
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, v := range s.Facts(Fired{}) {
+		order = append(order, v.(*Fired).rule)
+	}
+
+	return order
+}
+
+type Fired struct {
+	rule string
+}
+
+func recordingRule(name string, condition krools.ConditionFn) *krools.RuleHandle {
+	return krools.NewInlineRule(name, condition, func(ctx krools.Context) error {
+		ctx.Insert(Fired{rule: name})
+		return nil
+	}).Deactivate()
+}
+
+func TestConflictResolvers(t *testing.T) {
+	rules := func() *krools.KnowledgeBase {
+		return krools.NewKnowledgeBase("agenda base").
+			Add(recordingRule("plain", func(ctx krools.Context) (bool, error) {
+				return krools.Has[Trigger](ctx), nil
+			}).Salience(1)).
+			Add(recordingRule("older", func(ctx krools.Context) (bool, error) {
+				return krools.Has[Older](ctx), nil
+			})).
+			Add(recordingRule("newer and older", func(ctx krools.Context) (bool, error) {
+				return krools.Has[Newer](ctx) && krools.Has[Older](ctx), nil
+			}))
+	}
+
+	prepare := func(s *krools.Session) {
+		krools.Set(s, Trigger{})
+		krools.Set(s, Older{})
+		krools.Set(s, Newer{})
+	}
+
+	cases := map[string]struct {
+		resolver krools.ConflictResolver
+		want     []string
+	}{
+		"salience": {
+			resolver: krools.SalienceResolver(),
+			want:     []string{"plain", "older", "newer and older"},
+		},
+		"recency": {
+			resolver: krools.RecencyResolver(),
+			want:     []string{"newer and older", "older", "plain"},
+		},
+		"specificity then salience": {
+			resolver: krools.ChainResolvers(krools.SpecificityResolver(), krools.SalienceResolver()),
+			want:     []string{"newer and older", "plain", "older"},
+		},
+		"fifo": {
+			resolver: krools.FIFOResolver(),
+			want:     []string{"plain", "older", "newer and older"},
+		},
+		"lifo": {
+			resolver: krools.LIFOResolver(),
+			want:     []string{"newer and older", "older", "plain"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := firedOrder(t, rules().SetConflictResolver(c.resolver), prepare); !slices.Equal(got, c.want) {
+				t.Fatalf("unexpected order: %v", got)
+			}
+		})
+	}
+}
+
+func TestRandomResolver(t *testing.T) {
+	k := krools.NewKnowledgeBase("agenda base")
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		k.Add(recordingRule(name, func(ctx krools.Context) (bool, error) { return true, nil }))
+	}
+
+	first := firedOrder(t, k.SetConflictResolver(krools.RandomResolver(42)), func(*krools.Session) {})
+	second := firedOrder(t, k.SetConflictResolver(krools.RandomResolver(42)), func(*krools.Session) {})
+
+	if !slices.Equal(first, second) {
+		t.Fatalf("orders differ for the same seed: %v and %v", first, second)
+	}
+}
+
+func TestFIFOResolver_AcrossCycles(t *testing.T) {
+	for resolver, want := range map[string][]string{
+		// The condition of "satisfied first" holds since the first cycle, so it goes first in the second cycle too.
+		"fifo": {"satisfied first", "satisfied first", "added first"},
+		"lifo": {"satisfied first", "added first", "satisfied first"},
+	} {
+		k := krools.NewKnowledgeBase("agenda base").
+			Add(recordingRule("added first", func(ctx krools.Context) (bool, error) {
+				return krools.Has[Newer](ctx), nil
+			})).
+			Add(krools.NewInlineRule("satisfied first", func(ctx krools.Context) (bool, error) {
+				c, _ := krools.Get[Counter](ctx)
+				return c.n < 2, nil
+			}, func(ctx krools.Context) error {
+				krools.Handle[Counter](ctx).n++
+				krools.Set(ctx, Newer{})
+				ctx.Insert(Fired{rule: "satisfied first"})
+				return nil
+			}))
+
+		k.SetConflictResolver(krools.FIFOResolver())
+		if resolver == "lifo" {
+			k.SetConflictResolver(krools.LIFOResolver())
+		}
+
+		if got := firedOrder(t, k, func(s *krools.Session) { krools.Set(s, Counter{}) }); !slices.Equal(got, want) {
+			t.Errorf("%s: unexpected order: %v", resolver, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package krools
+
+import (
+	"fmt"
+)
+
+// Build validates the knowledge base and returns its built copy: rules are copied and compiled once and the copy
+// can't be changed anymore, setters and Add panic. Sessions of a built knowledge base share its rules instead of
+// copying them, so NewSession and Execute are cheap and the built knowledge base is safe to use from many goroutines.
+// The knowledge base itself stays a builder, it may be changed and built again.
+func (k *KnowledgeBase) Build() (*KnowledgeBase, error) {
+	if k.built {
+		return k, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+
+	rules := k.copy()
+
+	return &KnowledgeBase{
+		name:             k.name,
+		units:            rules.units,
+		unitsOrder:       rules.unitsOrder,
+		activationUnits:  rules.activationUnits,
+		deactivatedUnits: rules.deactivatedUnits,
+		expirations:      rules.expirations,
+		network:          rules.network,
+		resolver:         k.resolver,
+		stateless:        rules,
+		listeners:        k.listeners.clone(),
+		tracer:           k.tracer,
+		logger:           k.logger,
+		metricsExporter:  k.metricsExporter,
+		built:            true,
+	}, nil
+}
+
+// Built reports if the knowledge base is made by Build and can't be changed.
+func (k *KnowledgeBase) Built() bool {
+	return k.built
+}
+
+func (k *KnowledgeBase) mustNotBeBuilt() {
+	if k.built {
+		panic(fmt.Sprintf("knowledge base '%s' is built and can't be changed", k.name))
+	}
+}
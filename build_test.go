@@ -0,0 +1,116 @@
+package krools_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type ruleRecorder struct {
+	krools.DefaultAgendaEventListener
+
+	mu    sync.Mutex
+	rules []*krools.RuleHandle
+}
+
+func (r *ruleRecorder) BeforeActionExecuted(e krools.ActionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules = append(r.rules, e.Rule)
+}
+
+func TestKnowledgeBase_BuildSharesRules(t *testing.T) {
+	recorder := &ruleRecorder{}
+
+	builder := krools.NewKnowledgeBase("shared").
+		AddAgendaEventListener(recorder).
+		Add(krools.NewInlineRule("discount", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Trigger](ctx), nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Discount{percent: 10})
+			return nil
+		}).Deactivate().ActivationUnit("discount")).
+		Add(krools.NewInlineRule("no discount", nil, noop).Salience(-1).ActivationUnit("discount"))
+
+	kb, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !kb.Built() || builder.Built() {
+		t.Fatal("expected only the result of Build to be built")
+	}
+
+	if again, err := kb.Build(); err != nil || again != kb {
+		t.Fatalf("expected built knowledge base to build itself, got %v", err)
+	}
+
+	builder.Add(krools.NewInlineRule("added later", nil, noop).Salience(100).Deactivate())
+
+	var wg sync.WaitGroup
+
+	for range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			s := kb.NewSession()
+			s.Set(Trigger{})
+
+			if err := s.FireAllRules(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if d, ok := krools.Get[Discount](s); !ok || d.percent != 10 {
+				t.Errorf("expected discount, got %v", d)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(recorder.rules) != 16 {
+		t.Fatalf("expected 16 executions, got %d", len(recorder.rules))
+	}
+
+	for _, rule := range recorder.rules {
+		if rule != recorder.rules[0] {
+			t.Fatalf("expected sessions to share rule '%s', got rule '%s'", recorder.rules[0].Name(), rule.Name())
+		}
+	}
+
+	if recorder.rules[0].Name() != "discount" {
+		t.Errorf("expected rule 'discount' to fire, got '%s'", recorder.rules[0].Name())
+	}
+}
+
+func TestKnowledgeBase_BuiltPanicsOnChange(t *testing.T) {
+	kb, err := krools.NewKnowledgeBase("frozen").Add(krools.NewInlineRule("rule", nil, noop).Deactivate()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, change := range map[string]func(){
+		"Add":                 func() { kb.Add(krools.NewInlineRule("other", nil, noop)) },
+		"AddUnit":             func() { kb.AddUnit("unit", krools.NewInlineRule("other", nil, noop)) },
+		"SetDeactivatedUnits": func() { kb.SetDeactivatedUnits(krools.UnitMAIN) },
+		"SetConflictResolver": func() { kb.SetConflictResolver(krools.SalienceResolver()) },
+		"ExpireEvents":        func() { kb.ExpireEvents(Trigger{}, 0) },
+		"SetLogger":           func() { kb.SetLogger(nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+
+			change()
+		})
+	}
+}
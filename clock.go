@@ -0,0 +1,104 @@
+package krools
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time to a session. Time windows, expiration of events and timers of rules use the clock of the
+// session, so a pseudo-clock makes them deterministic in tests.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock returns the clock of the system. It's the default clock of a session.
+func RealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// PseudoClock is a clock advanced manually. It's safe for concurrent use.
+type PseudoClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []pseudoWaiter
+}
+
+type pseudoWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewPseudoClock returns a clock stopped at the time.
+func NewPseudoClock(now time.Time) *PseudoClock {
+	return &PseudoClock{now: now}
+}
+
+func (c *PseudoClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *PseudoClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, pseudoWaiter{at: c.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Advance moves the clock forward by the duration and returns the new time.
+func (c *PseudoClock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(c.now.Add(d))
+
+	return c.now
+}
+
+// Set sets the time of the clock, the time must not be before the current one.
+func (c *PseudoClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(now)
+}
+
+func (c *PseudoClock) set(now time.Time) {
+	if now.Before(c.now) {
+		panic("pseudo clock can't go back")
+	}
+
+	c.now = now
+
+	waiters := c.waiters[:0]
+
+	for _, w := range c.waiters {
+		if w.at.After(now) {
+			waiters = append(waiters, w)
+			continue
+		}
+
+		w.ch <- now
+	}
+
+	c.waiters = waiters
+}
@@ -0,0 +1,88 @@
+package krools_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+func TestPseudoClock_After(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := krools.NewPseudoClock(start)
+
+	soon := c.After(time.Minute)
+	later := c.After(time.Hour)
+
+	select {
+	case <-soon:
+		t.Fatal("fired before advance")
+	default:
+	}
+
+	c.Advance(30 * time.Minute)
+
+	select {
+	case at := <-soon:
+		if !at.Equal(start.Add(30 * time.Minute)) {
+			t.Fatalf("unexpected time: %s", at)
+		}
+	default:
+		t.Fatal("not fired after advance")
+	}
+
+	select {
+	case <-later:
+		t.Fatal("fired too early")
+	default:
+	}
+
+	c.Set(start.Add(2 * time.Hour))
+
+	select {
+	case <-later:
+	default:
+		t.Fatal("not fired after set")
+	}
+}
+
+type Deadline struct {
+	at time.Time
+}
+
+type Overdue struct{}
+
+func TestPseudoClock_Session(t *testing.T) {
+	clock := krools.NewPseudoClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	k := krools.NewKnowledgeBase("clock base").
+		Add(krools.NewInlineRule("overdue", func(ctx krools.Context) (bool, error) {
+			d, ok := krools.Get[Deadline](ctx)
+			return ok && ctx.Clock().Now().After(d.at) && !krools.Has[Overdue](ctx), nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Overdue{})
+			return nil
+		}))
+
+	s := k.NewSession().SetClock(clock)
+	krools.Set(s, Deadline{at: clock.Now().Add(time.Hour)})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if krools.Has[Overdue](s) {
+		t.Fatal("overdue too early")
+	}
+
+	clock.Advance(2 * time.Hour)
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !krools.Has[Overdue](s) {
+		t.Fatal("not overdue")
+	}
+}
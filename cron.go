@@ -0,0 +1,162 @@
+package krools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression of five fields: minute, hour, day of month, month and day of week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// every is set by the "@every <duration>" expression instead of fields.
+	every time.Duration
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a cron expression like "*/15 9-17 * * 1-5", a descriptor like "@daily" or "@every 90s".
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if d, ok := strings.CutPrefix(expr, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("parse cron expression '%s': %w", expr, err)
+		}
+
+		if every <= 0 {
+			return nil, fmt.Errorf("parse cron expression '%s': duration must be positive", expr)
+		}
+
+		return &cronSchedule{every: every}, nil
+	}
+
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("parse cron expression '%s': expected 5 fields, got %d", expr, len(fields))
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+	var sets [5]uint64
+
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("parse cron expression '%s': field %d: %w", expr, i+1, err)
+		}
+
+		sets[i] = set
+	}
+
+	s := &cronSchedule{minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4]}
+
+	// Sunday is both 0 and 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+func parseCronField(field string, low, high int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step '%s'", stepStr)
+			}
+		}
+
+		from, to := low, high
+
+		if rng != "*" {
+			fromStr, toStr, isRange := strings.Cut(rng, "-")
+
+			var err error
+			if from, err = strconv.Atoi(fromStr); err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", fromStr)
+			}
+
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(toStr); err != nil {
+					return 0, fmt.Errorf("invalid value '%s'", toStr)
+				}
+			} else if hasStep {
+				to = high
+			}
+		}
+
+		if from < low || to > high || from > to {
+			return 0, fmt.Errorf("range '%s' is out of %d-%d", rng, low, high)
+		}
+
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+// next returns the first time of the schedule after passed time.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches matches the day of month or the day of week if both are restricted, as cron does.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	const allDom, allDow = uint64(0xfffffffe), uint64(0xff)
+
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+
+	if s.dom == allDom || s.dow == allDow {
+		return dom && dow
+	}
+
+	return dom || dow
+}
@@ -0,0 +1,48 @@
+package krools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	from := time.Date(2024, 1, 1, 10, 7, 30, 0, time.UTC) // Monday
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"30 8-11 * * 1-5", time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 3", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"5,10 12 1 1,6 *", time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)},
+		{"0 0 1 6 *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 1, 1, 10, 9, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := parseCron(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+
+		if got := s.next(from); !got.Equal(c.want) {
+			t.Errorf("%s: expected %s, got %s", c.expr, c.want, got)
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "@every", "@every -1m", "@weekly2"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package krools
+
+// dependencies remembers conditions that were not satisfied along with versions of types they read, so such
+// conditions are not evaluated again until any of those types is changed.
+type dependencies struct {
+	unsatisfied map[*RuleHandle]map[string]uint64
+}
+
+func newDependencies() *dependencies {
+	return &dependencies{unsatisfied: make(map[*RuleHandle]map[string]uint64)}
+}
+
+// unchanged reports if the condition of the rule is known to be unsatisfied and nothing it read has changed since.
+func (d *dependencies) unchanged(rule *RuleHandle, c *structTypeContainer) bool {
+	versions, ok := d.unsatisfied[rule]
+	if !ok {
+		return false
+	}
+
+	for key, version := range versions {
+		if c.versions[key] != version {
+			delete(d.unsatisfied, rule)
+			return false
+		}
+	}
+
+	return true
+}
+
+// remember stores the rule's unsatisfied condition reads. Conditions that read nothing from working memory are not
+// remembered as they may depend on anything else.
+func (d *dependencies) remember(rule *RuleHandle, reads map[string]struct{}, c *structTypeContainer) {
+	if len(reads) == 0 {
+		return
+	}
+
+	versions := make(map[string]uint64, len(reads))
+	for key := range reads {
+		versions[key] = c.versions[key]
+	}
+
+	d.unsatisfied[rule] = versions
+}
@@ -0,0 +1,150 @@
+package krools_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Counter struct {
+	n int
+}
+
+type Unrelated struct{}
+
+func TestDependencyTracking(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		var unrelatedEvaluations int
+
+		k := krools.NewKnowledgeBase("dependencies base").
+			Add(krools.NewInlineRule("count", func(ctx krools.Context) (bool, error) {
+				c, ok := krools.Get[Counter](ctx)
+				return ok && c.n < 10, nil
+			}, func(ctx krools.Context) error {
+				krools.Handle[Counter](ctx).n++
+				return nil
+			})).
+			Add(krools.NewInlineRule("unrelated", func(ctx krools.Context) (bool, error) {
+				unrelatedEvaluations++
+				return krools.Has[Unrelated](ctx), nil
+			}, nil))
+
+		s := k.NewSession().SetDependencyTracking(enabled)
+		krools.Set(s, Counter{})
+
+		if err := s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if c := krools.MustGet[Counter](s); c.n != 10 {
+			t.Fatalf("unexpected counter: %d", c.n)
+		}
+
+		if enabled && unrelatedEvaluations != 1 {
+			t.Fatalf("unrelated rule is evaluated %d times", unrelatedEvaluations)
+		}
+
+		if !enabled && unrelatedEvaluations != 11 {
+			t.Fatalf("unrelated rule is evaluated %d times", unrelatedEvaluations)
+		}
+	}
+}
+
+func TestDependencyTracking_Volatile(t *testing.T) {
+	var evaluations int
+
+	k := krools.NewKnowledgeBase("dependencies base").
+		Add(krools.NewInlineRule("count", func(ctx krools.Context) (bool, error) {
+			c, ok := krools.Get[Counter](ctx)
+			return ok && c.n < 3, nil
+		}, func(ctx krools.Context) error {
+			krools.Handle[Counter](ctx).n++
+			return nil
+		})).
+		Add(krools.NewInlineRule("volatile", func(ctx krools.Context) (bool, error) {
+			evaluations++
+			return ctx.Context().Err() != nil && krools.Has[Unrelated](ctx), nil
+		}, nil))
+
+	s := k.NewSession().SetDependencyTracking(true)
+	krools.Set(s, Counter{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if evaluations != 4 {
+		t.Fatalf("volatile rule is evaluated %d times", evaluations)
+	}
+}
+
+func TestDependencyTracking_OutsideWorkingMemory(t *testing.T) {
+	fire := func(configure func(s *krools.Session)) bool {
+		var flag, done bool
+
+		k := krools.NewKnowledgeBase("dependencies base").
+			Add(krools.NewInlineRule("done", func(ctx krools.Context) (bool, error) {
+				return krools.Has[Counter](ctx) && flag, nil
+			}, func(krools.Context) error {
+				done = true
+				return nil
+			}).Salience(10).Deactivate()).
+			Add(krools.NewInlineRule("flag", nil, func(krools.Context) error {
+				flag = true
+				return nil
+			}).Deactivate())
+
+		s := k.NewSession()
+		configure(s)
+		krools.Set(s, Counter{})
+
+		if err := s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		return done
+	}
+
+	byDefault := fire(func(*krools.Session) {})
+	disabled := fire(func(s *krools.Session) { s.SetDependencyTracking(false) })
+	enabled := fire(func(s *krools.Session) { s.SetDependencyTracking(true) })
+
+	if !byDefault || byDefault != disabled {
+		t.Errorf("expected rule to fire by default as without tracking, got %v and %v", byDefault, disabled)
+	}
+
+	if enabled {
+		t.Error("expected tracking to skip the condition depending on a variable")
+	}
+}
+
+func TestDependencyTracking_ReadsInActions(t *testing.T) {
+	var evaluations int
+
+	k := krools.NewKnowledgeBase("dependencies base").
+		Add(krools.NewInlineRule("count", func(ctx krools.Context) (bool, error) {
+			c, ok := krools.Get[Counter](ctx)
+			return ok && c.n < 3, nil
+		}, func(ctx krools.Context) error {
+			_, _ = krools.Get[Unrelated](ctx)
+			krools.Handle[Counter](ctx).n++
+			return nil
+		})).
+		Add(krools.NewInlineRule("unrelated", func(ctx krools.Context) (bool, error) {
+			evaluations++
+			return !krools.Has[Unrelated](ctx), nil
+		}, nil))
+
+	s := k.NewSession().SetDependencyTracking(true)
+	krools.Set(s, Counter{})
+	krools.Set(s, Unrelated{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if evaluations != 1 {
+		t.Fatalf("reading a copy in actions changed the fact, unrelated rule is evaluated %d times", evaluations)
+	}
+}
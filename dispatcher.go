@@ -0,0 +1,72 @@
+package krools
+
+import (
+	"context"
+	"iter"
+	"slices"
+)
+
+// Executor fires all rules of a session once.
+type Executor func(ctx context.Context) error
+
+// Dispatcher manages execution of a session itself by calling fireAllRules as many times as it needs, so it can
+// iterate over items or handle a stream of data preserving other facts of the session between executions. Pass it
+// as an option to Session.FireAllRules.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, session *Session, fireAllRules Executor) error
+}
+
+type DispatcherFn func(ctx context.Context, session *Session, fireAllRules Executor) error
+
+func (f DispatcherFn) Dispatch(ctx context.Context, session *Session, fireAllRules Executor) error {
+	return f(ctx, session, fireAllRules)
+}
+
+// SliceDispatcher sets every item into the session and fires all rules for it. The last item is deleted after all.
+// Items must be structs.
+func SliceDispatcher[T any](items []T) Dispatcher {
+	return SeqDispatcher(slices.Values(items))
+}
+
+// SeqDispatcher sets every item of the sequence into the session and fires all rules for it. The last item is
+// deleted after all. Items must be structs.
+func SeqDispatcher[T any](items iter.Seq[T]) Dispatcher {
+	return DispatcherFn(func(ctx context.Context, session *Session, fireAllRules Executor) error {
+		defer Delete[T](session)
+
+		for item := range items {
+			Set(session, item)
+
+			if err := fireAllRules(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ChannelDispatcher sets every item received from the channel into the session and fires all rules for it until the
+// channel is closed or the context is done. The last item is deleted after all. Items must be structs.
+func ChannelDispatcher[T any](items <-chan T) Dispatcher {
+	return DispatcherFn(func(ctx context.Context, session *Session, fireAllRules Executor) error {
+		defer Delete[T](session)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case item, ok := <-items:
+				if !ok {
+					return nil
+				}
+
+				Set(session, item)
+
+				if err := fireAllRules(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
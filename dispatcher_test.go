@@ -0,0 +1,103 @@
+package krools_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Word struct {
+	value string
+}
+
+type WordsWithoutA struct {
+	words []string
+}
+
+func wordsBase() *krools.KnowledgeBase {
+	return krools.NewKnowledgeBase("words base").
+		Add(krools.NewInlineRule("add the word without A", func(ctx krools.Context) (bool, error) {
+			w, ok := krools.Get[Word](ctx)
+			return ok && !strings.Contains(w.value, "a"), nil
+		}, func(ctx krools.Context) error {
+			w := krools.MustGet[Word](ctx)
+			krools.Delete[Word](ctx)
+
+			res, _ := krools.Get[WordsWithoutA](ctx)
+			res.words = append(res.words, w.value)
+			krools.Set(ctx, res)
+
+			return nil
+		}))
+}
+
+func TestDispatchers(t *testing.T) {
+	words := []string{"implements", "interface", "order", "example", "imagine"}
+
+	cases := map[string]func() krools.Dispatcher{
+		"slice": func() krools.Dispatcher {
+			var items []Word
+			for _, w := range words {
+				items = append(items, Word{value: w})
+			}
+
+			return krools.SliceDispatcher(items)
+		},
+		"seq": func() krools.Dispatcher {
+			return krools.SeqDispatcher(func(yield func(Word) bool) {
+				for _, w := range words {
+					if !yield(Word{value: w}) {
+						return
+					}
+				}
+			})
+		},
+		"channel": func() krools.Dispatcher {
+			ch := make(chan Word)
+			go func() {
+				defer close(ch)
+
+				for _, w := range words {
+					ch <- Word{value: w}
+				}
+			}()
+
+			return krools.ChannelDispatcher(ch)
+		},
+	}
+
+	for name, dispatcher := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := wordsBase().NewSession()
+
+			if err := s.FireAllRules(context.Background(), dispatcher()); err != nil {
+				t.Fatal(err)
+			}
+
+			res := krools.MustGet[WordsWithoutA](s)
+			if !slices.Equal(res.words, []string{"implements", "order"}) {
+				t.Fatalf("unexpected words: %v", res.words)
+			}
+
+			if krools.Has[Word](s) {
+				t.Fatal("the last item is not deleted")
+			}
+		})
+	}
+}
+
+func TestFireAllRules_UnsupportedOption(t *testing.T) {
+	s := wordsBase().NewSession()
+
+	if err := s.FireAllRules(context.Background(), "option"); err == nil {
+		t.Fatal("unsupported option is accepted")
+	}
+
+	d := krools.SliceDispatcher([]Word{})
+	if err := s.FireAllRules(context.Background(), d, d); err == nil {
+		t.Fatal("second dispatcher is accepted")
+	}
+}
@@ -0,0 +1,352 @@
+// Package dtable compiles decision tables into rules. A table is a grid where every row is a rule, the first row
+// declares kinds of columns and the second one their templates:
+//
+//	NAME,       CONDITION,        CONDITION,     ACTION
+//	,           Order.Total,      Customer.Tier, set Discount{Percent: $}
+//	gold large, >= 1000,          gold,          15
+//	large,      >= 1000,          -,             10
+//	small,      >= 100,           ,              5
+//
+// A condition cell is appended to the template, so it may start with an operator like ">= 1000" or "in [1, 2]", and
+// a cell without an operator is compared with ==. If the template contains $ the cell replaces it instead. An action
+// cell replaces $ in the template of the action. Values of cells, after an operator if any, are literals: numbers,
+// true, false and quoted strings are taken as they are and anything else is a string, so gold means "gold" and
+// 2024-01 means "2024-01". A value starting with = is an expression, like "=Order.Total * 0.1", and a list after in
+// is written as in the expression language. Empty cells and cells with - are skipped. Rows with empty cells only and
+// rows starting with # are ignored. Rows without a NAME column are named after the table and the number of the row.
+package dtable
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/expr"
+)
+
+// HitPolicy decides which rules of matching rows are executed.
+type HitPolicy int
+
+const (
+	// First executes only the first matching row. The condition of a row holds only if no row before it matches, so
+	// it doesn't depend on the conflict resolver of the session.
+	First HitPolicy = iota
+	// Unique executes the only matching row, firing fails if more than one row matches.
+	Unique
+	// Collect executes all matching rows. They are executed in order of rows with the salience resolver only, other
+	// conflict resolvers of the session order them their way.
+	Collect
+)
+
+func (p HitPolicy) String() string {
+	switch p {
+	case First:
+		return "first"
+	case Unique:
+		return "unique"
+	case Collect:
+		return "collect"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrNotUnique is returned by firing when more than one row of a table with the Unique policy matches.
+var ErrNotUnique = errors.New("more than one row matches")
+
+// Table is a compiled decision table.
+type Table struct {
+	Name   string
+	Policy HitPolicy
+	// Rules are rules of rows in order, salience of rules decreases from the first row to the last one.
+	Rules []*krools.RuleHandle
+}
+
+// AddTo adds rules of the table to the unit with the name of the table.
+func (t *Table) AddTo(k *krools.KnowledgeBase) *krools.KnowledgeBase {
+	return k.AddUnit(t.Name, t.Rules...)
+}
+
+// Compile reads the CSV table and compiles it.
+func Compile(name string, r io.Reader, policy HitPolicy, types *expr.Types) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read decision table '%s': %w", name, err)
+	}
+
+	return CompileRecords(name, records, policy, types)
+}
+
+// CompileRecords compiles the table of records, so tables may be read from any kind of spreadsheet.
+func CompileRecords(name string, records [][]string, policy HitPolicy, types *expr.Types) (*Table, error) {
+	if len(records) < 2 {
+		return nil, fmt.Errorf("decision table '%s' has no header rows", name)
+	}
+
+	columns, err := parseHeader(records[0], records[1])
+	if err != nil {
+		return nil, fmt.Errorf("decision table '%s': %w", name, err)
+	}
+
+	var rows []*row
+
+	names := make(map[string]int)
+
+	for i, record := range records[2:] {
+		if ignored(record) {
+			continue
+		}
+
+		r, err := compileRow(name, i+3, columns, record, types)
+		if err != nil {
+			return nil, fmt.Errorf("decision table '%s': %w", name, err)
+		}
+
+		if number, ok := names[r.name]; ok {
+			return nil, fmt.Errorf("decision table '%s': row %d has the same name '%s' as row %d", name, r.number, r.name,
+				number)
+		}
+
+		names[r.name] = r.number
+		rows = append(rows, r)
+	}
+
+	t := &Table{Name: name, Policy: policy}
+
+	for i, r := range rows {
+		rule := krools.NewInlineRule(r.name, r.when(policy, rows[:i]), r.action(policy, rows)).
+			Salience(len(rows) - i).
+			Deactivate()
+
+		if policy != Collect {
+			rule.ActivationUnit(name)
+		}
+
+		t.Rules = append(t.Rules, rule)
+	}
+
+	return t, nil
+}
+
+type columnKind int
+
+const (
+	conditionColumn columnKind = iota
+	actionColumn
+	nameColumn
+)
+
+type column struct {
+	kind     columnKind
+	template string
+}
+
+func parseHeader(kinds, templates []string) ([]column, error) {
+	columns := make([]column, 0, len(kinds))
+
+	for i, kind := range kinds {
+		c := column{}
+
+		if i < len(templates) {
+			c.template = strings.TrimSpace(templates[i])
+		}
+
+		switch strings.ToUpper(strings.TrimSpace(kind)) {
+		case "CONDITION":
+			c.kind = conditionColumn
+		case "ACTION":
+			c.kind = actionColumn
+		case "NAME":
+			c.kind = nameColumn
+		default:
+			return nil, fmt.Errorf("column %d has unknown kind '%s'", i+1, kind)
+		}
+
+		if c.kind != nameColumn && c.template == "" {
+			return nil, fmt.Errorf("column %d has no template", i+1)
+		}
+
+		columns = append(columns, c)
+	}
+
+	return columns, nil
+}
+
+func ignored(record []string) bool {
+	if len(record) > 0 && strings.HasPrefix(strings.TrimSpace(record[0]), "#") {
+		return true
+	}
+
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+type row struct {
+	number    int
+	name      string
+	condition krools.ConditionFn
+	then      krools.ActionFn
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "<", ">", "in "}
+
+func compileRow(table string, number int, columns []column, record []string, types *expr.Types) (*row, error) {
+	var (
+		conditions []string
+		statements []string
+	)
+
+	r := &row{number: number, name: fmt.Sprintf("%s row %d", table, number)}
+
+	for i, c := range columns {
+		if i >= len(record) {
+			break
+		}
+
+		cell := strings.TrimSpace(record[i])
+		if cell == "" || cell == "-" {
+			continue
+		}
+
+		switch c.kind {
+		case nameColumn:
+			r.name = cell
+		case conditionColumn:
+			switch {
+			case strings.Contains(c.template, "$"):
+				conditions = append(conditions, strings.ReplaceAll(c.template, "$", operand(cell)))
+			case hasOperator(cell):
+				conditions = append(conditions, c.template+" "+withOperator(cell))
+			default:
+				conditions = append(conditions, c.template+" == "+operand(cell))
+			}
+		case actionColumn:
+			statements = append(statements, strings.ReplaceAll(c.template, "$", operand(cell)))
+		}
+	}
+
+	src := "true"
+	if len(conditions) > 0 {
+		src = "(" + strings.Join(conditions, ") && (") + ")"
+	}
+
+	var err error
+
+	if r.condition, err = expr.Condition(src, types); err != nil {
+		return nil, fmt.Errorf("compile condition of row %d: %w", number, err)
+	}
+
+	if r.then, err = expr.Action(statements, types); err != nil {
+		return nil, fmt.Errorf("compile action of row %d: %w", number, err)
+	}
+
+	return r, nil
+}
+
+func hasOperator(cell string) bool {
+	for _, op := range operators {
+		if strings.HasPrefix(cell, op) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withOperator returns the cell starting with an operator with its value as an operand, lists after in are kept.
+func withOperator(cell string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(cell, op) {
+			value := strings.TrimSpace(strings.TrimPrefix(cell, op))
+			if op == "in " || value == "" {
+				return op + value
+			}
+
+			return op + " " + operand(value)
+		}
+	}
+
+	return cell
+}
+
+var number = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// operand returns the value of a cell as a literal or an expression if it starts with =.
+func operand(cell string) string {
+	switch {
+	case strings.HasPrefix(cell, "="):
+		return strings.TrimSpace(cell[1:])
+	case number.MatchString(cell), cell == "true", cell == "false":
+		return cell
+	case len(cell) > 1 && strings.HasPrefix(cell, `"`) && strings.HasSuffix(cell, `"`):
+		return cell
+	default:
+		return strconv.Quote(cell)
+	}
+}
+
+// when returns the condition of the row, with the First policy it doesn't hold if any of rows before matches.
+func (r *row) when(policy HitPolicy, before []*row) krools.ConditionFn {
+	if policy != First {
+		return r.condition
+	}
+
+	return func(ctx krools.Context) (bool, error) {
+		if ok, err := r.condition(ctx); !ok || err != nil {
+			return false, err
+		}
+
+		for _, other := range before {
+			ok, err := other.condition(ctx)
+			if err != nil {
+				return false, err
+			}
+
+			if ok {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	}
+}
+
+// action returns the action of the row, with the Unique policy it fails if any other row matches too.
+func (r *row) action(policy HitPolicy, rows []*row) krools.ActionFn {
+	if policy != Unique {
+		return r.then
+	}
+
+	return func(ctx krools.Context) error {
+		for _, other := range rows {
+			if other == r {
+				continue
+			}
+
+			ok, err := other.condition(ctx)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				return fmt.Errorf("%w: rows %d and %d", ErrNotUnique, r.number, other.number)
+			}
+		}
+
+		return r.then(ctx)
+	}
+}
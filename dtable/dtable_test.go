@@ -0,0 +1,195 @@
+package dtable_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/dtable"
+	"github.com/krocos/krools/v2/expr"
+)
+
+type Customer struct {
+	Tier string
+}
+
+type Order struct {
+	Total float64
+}
+
+type Discount struct {
+	Percent int
+	Reason  string
+}
+
+const pricing = `NAME,       CONDITION,   CONDITION,     ACTION,                   ACTION
+,           Order.Total, Customer.Tier, set Discount{Percent: $}, insert Discount{Percent: $}
+# discounts of gold customers go first
+gold large, >= 1000,     gold,          15,                       15
+,,,,
+large,      >= 1000,     -,             10,                       10
+small,      >= 100,      ,              5,                        5
+`
+
+func types() *expr.Types {
+	return expr.NewTypes().
+		Register("Customer", Customer{}).
+		Register("Order", Order{}).
+		Register("Discount", Discount{})
+}
+
+func fire(
+	t *testing.T,
+	policy dtable.HitPolicy,
+	tier string,
+	total float64,
+	resolvers ...krools.ConflictResolver,
+) (int, []int, error) {
+	t.Helper()
+
+	table, err := dtable.Compile("pricing", strings.NewReader(pricing), policy, types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := table.AddTo(krools.NewKnowledgeBase("pricing base"))
+	for _, resolver := range resolvers {
+		k.SetConflictResolver(resolver)
+	}
+
+	s := k.NewSession()
+	krools.Set(s, Customer{Tier: tier})
+	krools.Set(s, Order{Total: total})
+
+	err = s.FireAllRules(context.Background())
+
+	var inserted []int
+	for _, v := range s.Facts(Discount{}) {
+		inserted = append(inserted, v.(*Discount).Percent)
+	}
+
+	d, _ := krools.Get[Discount](s)
+
+	return d.Percent, inserted, err
+}
+
+func TestCompile_First(t *testing.T) {
+	for _, c := range []struct {
+		tier    string
+		total   float64
+		percent int
+	}{
+		{"gold", 5000, 15},
+		{"silver", 5000, 10},
+		{"gold", 500, 5},
+		{"gold", 50, 0},
+	} {
+		percent, inserted, err := fire(t, dtable.First, c.tier, c.total)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if percent != c.percent || len(inserted) > 1 {
+			t.Errorf("%s %v: expected %d, got %d and %v", c.tier, c.total, c.percent, percent, inserted)
+		}
+	}
+}
+
+func TestCompile_FirstWithResolvers(t *testing.T) {
+	for name, resolver := range map[string]krools.ConflictResolver{
+		"recency": krools.RecencyResolver(),
+		"lifo":    krools.LIFOResolver(),
+		"random":  krools.RandomResolver(7),
+	} {
+		percent, inserted, err := fire(t, dtable.First, "gold", 5000, resolver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if percent != 15 || !slices.Equal(inserted, []int{15}) {
+			t.Errorf("%s: expected the first row only, got %d and %v", name, percent, inserted)
+		}
+	}
+}
+
+func TestCompile_Literals(t *testing.T) {
+	const periods = `CONDITION,     CONDITION,    ACTION
+Customer.Tier, Order.Total,  insert Discount{Percent: $}
+2024-01,       ,             1
+a-b,           ,             2
+"gold",        =Order.Total, 3
+`
+
+	table, err := dtable.Compile("periods", strings.NewReader(periods), dtable.Collect, types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for tier, want := range map[string][]int{"2024-01": {1}, "2023": nil, "a-b": {2}, "gold": {3}} {
+		s := table.AddTo(krools.NewKnowledgeBase("periods base")).NewSession()
+		krools.Set(s, Customer{Tier: tier})
+		krools.Set(s, Order{Total: 10})
+
+		if err = s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		var inserted []int
+		for _, v := range s.Facts(Discount{}) {
+			inserted = append(inserted, v.(*Discount).Percent)
+		}
+
+		if !slices.Equal(inserted, want) {
+			t.Errorf("%s: expected %v, got %v", tier, want, inserted)
+		}
+	}
+}
+
+func TestCompile_Collect(t *testing.T) {
+	_, inserted, err := fire(t, dtable.Collect, "gold", 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(inserted, []int{15, 10, 5}) {
+		t.Errorf("unexpected discounts: %v", inserted)
+	}
+}
+
+func TestCompile_Unique(t *testing.T) {
+	if _, _, err := fire(t, dtable.Unique, "gold", 5000); !errors.Is(err, dtable.ErrNotUnique) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	percent, _, err := fire(t, dtable.Unique, "silver", 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if percent != 5 {
+		t.Errorf("expected 5, got %d", percent)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for table, msg := range map[string]string{
+		"CONDITION\n":                             "has no header rows",
+		"RESULT\nOrder.Total\n1\n":                "unknown kind 'RESULT'",
+		"CONDITION\n\n":                           "has no header rows",
+		"CONDITION,ACTION\nOrder.Total,\n":        "column 2 has no template",
+		"CONDITION\nOrder.Totl\n1\n":              "compile condition of row 3",
+		"ACTION\nset Discount{Percnt: $}\n1\n":    "compile action of row 3",
+		"CONDITION\nOrder.Total\n\"unclosed\n":    "read decision table",
+		"CONDITION,ACTION\nOrder.Total,halt\n>\n": "compile condition of row 3",
+		"NAME,ACTION\n,halt\nlarge,\nlarge,\n":    "row 4 has the same name 'large' as row 3",
+		"NAME,ACTION\n,halt\n-,\nbroken row 3,\n": "row 4 has the same name 'broken row 3' as row 3",
+	} {
+		_, err := dtable.Compile("broken", strings.NewReader(table), dtable.First, types())
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%q: expected error with %q, got %v", table, msg, err)
+		}
+	}
+}
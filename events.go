@@ -0,0 +1,194 @@
+package krools
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// EventTime is the time span of an event. Start and End are equal for events that happen at a point in time.
+type EventTime struct {
+	Start time.Time
+	End   time.Time
+}
+
+// At returns the time of an event that happens at a point in time.
+func At(t time.Time) EventTime {
+	return EventTime{Start: t, End: t}
+}
+
+// Span returns the time of an event that lasts from start to end.
+func Span(start, end time.Time) EventTime {
+	return EventTime{Start: start, End: end}
+}
+
+// Before reports if the event ends before the other one starts. Optional bounds are the minimal and the maximal
+// distance between the end of the event and the start of the other one, by default the distance must be positive.
+func (e EventTime) Before(other EventTime, bounds ...time.Duration) bool {
+	return inBounds(other.Start.Sub(e.End), bounds)
+}
+
+// After reports if the event starts after the other one ends, optional bounds are as for Before.
+func (e EventTime) After(other EventTime, bounds ...time.Duration) bool {
+	return other.Before(e, bounds...)
+}
+
+// During reports if the event starts after the other one starts and ends before the other one ends.
+func (e EventTime) During(other EventTime) bool {
+	return other.Start.Before(e.Start) && e.End.Before(other.End)
+}
+
+// Includes reports if the other event happens during the event.
+func (e EventTime) Includes(other EventTime) bool {
+	return other.During(e)
+}
+
+// Within reports if the distance between events is not greater than d, overlapping events are at zero distance.
+func (e EventTime) Within(other EventTime, d time.Duration) bool {
+	switch {
+	case e.End.Before(other.Start):
+		return other.Start.Sub(e.End) <= d
+	case other.End.Before(e.Start):
+		return e.Start.Sub(other.End) <= d
+	default:
+		return true
+	}
+}
+
+func inBounds(d time.Duration, bounds []time.Duration) bool {
+	minimum, maximum := time.Duration(1), time.Duration(math.MaxInt64)
+
+	if len(bounds) > 0 {
+		minimum = bounds[0]
+	}
+
+	if len(bounds) > 1 {
+		maximum = bounds[1]
+	}
+
+	return d >= minimum && d <= maximum
+}
+
+// Event is an event of type T in working memory.
+type Event[T any] struct {
+	Handle FactHandle
+	Fact   *T
+	Time   EventTime
+}
+
+type event struct {
+	h FactHandle
+	v any
+	t EventTime
+}
+
+// eventMemory is implemented by working memories of the package which keep events.
+type eventMemory interface {
+	eventsKey(key string) []event
+	now() time.Time
+	window(key string, d time.Duration)
+}
+
+// Events returns all events of type T ordered by their start.
+func Events[T any](m WorkingMemory) []Event[T] {
+	em, ok := m.(eventMemory)
+	if !ok {
+		return nil
+	}
+
+	return typedEvents[T](em.eventsKey(keyOf[T]()))
+}
+
+// TimeWindow returns events of type T that ended within the last d ordered by their start. Using a time window
+// lets a session expire events of type T older than the longest window used if their expiration is not declared.
+func TimeWindow[T any](m WorkingMemory, d time.Duration) []Event[T] {
+	em, ok := m.(eventMemory)
+	if !ok {
+		return nil
+	}
+
+	key := keyOf[T]()
+	em.window(key, d)
+	since := em.now().Add(-d)
+
+	return typedEvents[T](slices.DeleteFunc(em.eventsKey(key), func(e event) bool {
+		return e.t.End.Before(since)
+	}))
+}
+
+// LengthWindow returns the last n events of type T ordered by their start. Using a length window stops a session
+// from inferring expiration of events of type T from time windows.
+func LengthWindow[T any](m WorkingMemory, n int) []Event[T] {
+	em, ok := m.(eventMemory)
+	if !ok {
+		return nil
+	}
+
+	key := keyOf[T]()
+	em.window(key, 0)
+	events := em.eventsKey(key)
+
+	return typedEvents[T](events[max(0, len(events)-n):])
+}
+
+func typedEvents[T any](events []event) []Event[T] {
+	typed := make([]Event[T], 0, len(events))
+	for _, e := range events {
+		typed = append(typed, Event[T]{Handle: e.h, Fact: e.v.(*T), Time: e.t})
+	}
+
+	return typed
+}
+
+// eventExpiry decides when events of a session are not needed anymore.
+type eventExpiry struct {
+	declared map[string]time.Duration
+	windows  map[string]time.Duration
+	lengths  map[string]struct{}
+}
+
+func newEventExpiry(declared map[string]time.Duration) *eventExpiry {
+	return &eventExpiry{
+		declared: declared,
+		windows:  make(map[string]time.Duration),
+		lengths:  make(map[string]struct{}),
+	}
+}
+
+// window records the use of a time window or of a length window if d is zero.
+func (e *eventExpiry) window(key string, d time.Duration) {
+	if d == 0 {
+		e.lengths[key] = struct{}{}
+	} else {
+		e.windows[key] = max(e.windows[key], d)
+	}
+}
+
+// expire retracts events that can't match anymore.
+func (e *eventExpiry) expire(c *structTypeContainer, now time.Time) {
+	keys := maps.Clone(e.windows)
+	for key := range e.declared {
+		keys[key] = 0
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		after, ok := e.declared[key]
+		if !ok {
+			if _, ok = e.lengths[key]; ok {
+				continue
+			}
+
+			after = e.windows[key]
+		}
+
+		c.expire(key, now.Add(-after))
+	}
+}
+
+func sortEvents(events []event) {
+	slices.SortStableFunc(events, func(a, b event) int {
+		return cmp.Or(a.t.Start.Compare(b.t.Start), cmp.Compare(a.h, b.h))
+	})
+}
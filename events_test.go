@@ -0,0 +1,108 @@
+package krools_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+type FailedLogin struct {
+	user string
+}
+
+type Lockout struct {
+	user string
+}
+
+func TestTimeWindow(t *testing.T) {
+	k := krools.NewKnowledgeBase("events base").
+		Add(krools.NewInlineRule("lock out after too many failed logins", func(ctx krools.Context) (bool, error) {
+			return len(krools.TimeWindow[FailedLogin](ctx, 5*time.Minute)) > 3 && !krools.Has[Lockout](ctx), nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Lockout{user: "user"})
+			return nil
+		}))
+
+	clock := krools.NewPseudoClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	s := k.NewSession().SetClock(clock)
+	now := clock.Now()
+
+	for _, ago := range []time.Duration{10, 4, 3, 2} {
+		s.InsertEvent(FailedLogin{user: "user"}, krools.At(now.Add(-ago*time.Minute)))
+	}
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if krools.Has[Lockout](s) {
+		t.Fatal("locked out too early")
+	}
+
+	if n := len(krools.Events[FailedLogin](s)); n != 3 {
+		t.Fatalf("old event is not expired, there are %d events", n)
+	}
+
+	s.InsertEvent(FailedLogin{user: "user"}, krools.At(now))
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !krools.Has[Lockout](s) {
+		t.Fatal("not locked out")
+	}
+}
+
+func TestLengthWindow(t *testing.T) {
+	clock := krools.NewPseudoClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	s := krools.NewKnowledgeBase("events base").
+		ExpireEvents(FailedLogin{}, time.Hour).
+		NewSession().
+		SetClock(clock)
+
+	now := clock.Now()
+
+	s.InsertEvent(FailedLogin{user: "expired"}, krools.At(now.Add(-2*time.Hour)))
+	s.InsertEvent(FailedLogin{user: "third"}, krools.At(now.Add(-time.Minute)))
+	s.InsertEvent(FailedLogin{user: "first"}, krools.At(now.Add(-3*time.Minute)))
+	s.InsertEvent(FailedLogin{user: "second"}, krools.At(now.Add(-2*time.Minute)))
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := krools.LengthWindow[FailedLogin](s, 2)
+	if len(events) != 2 || events[0].Fact.user != "second" || events[1].Fact.user != "third" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if n := len(krools.Events[FailedLogin](s)); n != 3 {
+		t.Fatalf("expired event is not retracted, there are %d events", n)
+	}
+}
+
+func TestTemporalOperators(t *testing.T) {
+	now := time.Now()
+	minute := krools.Span(now, now.Add(time.Minute))
+	later := krools.At(now.Add(3 * time.Minute))
+	inside := krools.At(now.Add(30 * time.Second))
+
+	if !minute.Before(later) || minute.After(later) || !later.After(minute) {
+		t.Fatal("unexpected order")
+	}
+
+	if !minute.Before(later, time.Minute, 2*time.Minute) || minute.Before(later, 3*time.Minute) {
+		t.Fatal("unexpected bounds")
+	}
+
+	if !inside.During(minute) || !minute.Includes(inside) || minute.During(inside) {
+		t.Fatal("unexpected inclusion")
+	}
+
+	if !later.Within(minute, 2*time.Minute) || later.Within(minute, time.Minute) || !inside.Within(minute, 0) {
+		t.Fatal("unexpected distance")
+	}
+}
@@ -0,0 +1,230 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+type statement interface {
+	exec(ctx krools.Context) error
+}
+
+// field is a field of a struct literal.
+type field struct {
+	index []int
+	name  string
+	typ   reflect.Type
+	v     node
+}
+
+func compileStatement(src string, types *Types) (statement, error) {
+	p, err := newParser(src, types)
+	if err != nil {
+		return nil, err
+	}
+
+	keyword := p.next()
+	if keyword.kind != tokenIdent {
+		return nil, fmt.Errorf("expected statement, got %s", keyword)
+	}
+
+	var s statement
+
+	switch keyword.text {
+	case "halt":
+		s = haltStatement{}
+	case "set", "modify", "insert", "delete":
+		t := p.next()
+
+		typ, ok := types.lookup(t.text)
+		if t.kind != tokenIdent || !ok {
+			return nil, fmt.Errorf("expected type name, got %s", t)
+		}
+
+		if keyword.text == "delete" {
+			s = &deleteStatement{ref: newFactRef(typ)}
+			break
+		}
+
+		fields, err := p.parseFields(typ)
+		if err != nil {
+			return nil, err
+		}
+
+		s = &writeStatement{op: keyword.text, ref: newFactRef(typ), fields: fields}
+	default:
+		return nil, fmt.Errorf("unknown statement %s", keyword)
+	}
+
+	if err = p.expectEOF(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+type haltStatement struct{}
+
+func (haltStatement) exec(ctx krools.Context) error {
+	ctx.Halt()
+
+	return nil
+}
+
+type deleteStatement struct {
+	ref *factRef
+}
+
+func (s *deleteStatement) exec(ctx krools.Context) error {
+	ctx.Delete(s.ref.zero)
+
+	return nil
+}
+
+type writeStatement struct {
+	op     string
+	ref    *factRef
+	fields []field
+}
+
+func (s *writeStatement) exec(ctx krools.Context) error {
+	v := reflect.New(s.ref.typ)
+
+	if s.op == "modify" {
+		current := ctx.Handle(s.ref.zero)
+		if current == nil {
+			return fmt.Errorf("modify missing %s", s.ref.typ)
+		}
+
+		v.Elem().Set(reflect.ValueOf(current).Elem())
+	}
+
+	for _, f := range s.fields {
+		x, err := f.v.eval(ctx)
+		if err != nil {
+			return fmt.Errorf("evaluate field %s: %w", f.name, err)
+		}
+
+		fv, err := convert(x, f.typ)
+		if err != nil {
+			return fmt.Errorf("assign field %s: %w", f.name, err)
+		}
+
+		v.Elem().FieldByIndex(f.index).Set(fv)
+	}
+
+	switch s.op {
+	case "insert":
+		ctx.Insert(v.Interface())
+	default:
+		ctx.Set(v.Interface())
+	}
+
+	return nil
+}
+
+// convert converts a value of an expression to a value of the type.
+func convert(x any, t reflect.Type) (reflect.Value, error) {
+	if x == nil {
+		return reflect.Zero(t), nil
+	}
+
+	v := reflect.ValueOf(x)
+
+	if t == durationType {
+		seconds, ok := toFloat(x)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%v is not a number of seconds", x)
+		}
+
+		d := math.Round(seconds * float64(time.Second))
+		if d < math.MinInt64 || d >= -math.MinInt64 {
+			return reflect.Value{}, fmt.Errorf("%v seconds overflow %s", x, t)
+		}
+
+		return reflect.ValueOf(time.Duration(d)), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := x.(float64); ok {
+			if f != math.Trunc(f) {
+				return reflect.Value{}, fmt.Errorf("%v is not an integer", x)
+			}
+
+			if f < math.MinInt64 || f >= -math.MinInt64 {
+				return reflect.Value{}, fmt.Errorf("%v overflows %s", x, t)
+			}
+
+			v = reflect.ValueOf(int64(f))
+		}
+
+		i, ok := v.Interface().(int64)
+		if !ok && v.Type() != t {
+			return reflect.Value{}, fmt.Errorf("%v is not an integer", x)
+		}
+
+		if ok && overflows(i, t) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", x, t)
+		}
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(x)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%v is not a number", x)
+		}
+
+		if reflect.Zero(t).OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", x, t)
+		}
+
+		v = reflect.ValueOf(x)
+	case reflect.String, reflect.Bool:
+		if v.Kind() != t.Kind() {
+			return reflect.Value{}, fmt.Errorf("%v is not a %s", x, t.Kind())
+		}
+	case reflect.Slice:
+		items, ok := x.([]any)
+		if !ok {
+			break
+		}
+
+		s := reflect.MakeSlice(t, 0, len(items))
+		for _, item := range items {
+			e, err := convert(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			s = reflect.Append(s, e)
+		}
+
+		return s, nil
+	}
+
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	if v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("%v is not assignable to %s", x, t)
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// overflows reports if the integer doesn't fit the integer type, negative integers don't fit unsigned types.
+func overflows(i int64, t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return i < 0 || reflect.Zero(t).OverflowUint(uint64(i))
+	default:
+		return reflect.Zero(t).OverflowInt(i)
+	}
+}
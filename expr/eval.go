@@ -0,0 +1,457 @@
+package expr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+type node interface {
+	eval(wm krools.WorkingMemory) (any, error)
+	// staticType returns the type of values of the node if it's known at compile time.
+	staticType() reflect.Type
+}
+
+type literal struct {
+	v any
+}
+
+func (l *literal) eval(krools.WorkingMemory) (any, error) { return l.v, nil }
+func (l *literal) staticType() reflect.Type               { return nil }
+
+// factRef refers to the value of a type set in working memory.
+type factRef struct {
+	typ  reflect.Type
+	zero any
+}
+
+func newFactRef(typ reflect.Type) *factRef {
+	return &factRef{typ: typ, zero: reflect.New(typ).Interface()}
+}
+
+func (f *factRef) eval(wm krools.WorkingMemory) (any, error) {
+	return wm.Handle(f.zero), nil
+}
+
+func (f *factRef) staticType() reflect.Type { return f.typ }
+
+type selector struct {
+	x     node
+	field string
+	typ   reflect.Type
+}
+
+func (s *selector) eval(wm krools.WorkingMemory) (any, error) {
+	x, err := s.x.eval(wm)
+	if err != nil || x == nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("select field %s of %T", s.field, x)
+	}
+
+	f, ok := v.Type().FieldByName(s.field)
+	if !ok || !f.IsExported() {
+		return nil, fmt.Errorf("type %s has no exported field %s", v.Type(), s.field)
+	}
+
+	return normalize(v.FieldByIndex(f.Index)), nil
+}
+
+func (s *selector) staticType() reflect.Type { return s.typ }
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (u *unary) eval(wm krools.WorkingMemory) (any, error) {
+	x, err := u.x.eval(wm)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.op {
+	case "!":
+		b, err := truth(x)
+		if err != nil {
+			return nil, err
+		}
+
+		return !b, nil
+	default:
+		switch x := x.(type) {
+		case nil:
+			return nil, nil
+		case int64:
+			return -x, nil
+		case float64:
+			return -x, nil
+		default:
+			return nil, fmt.Errorf("negate %v", x)
+		}
+	}
+}
+
+func (u *unary) staticType() reflect.Type { return nil }
+
+type binary struct {
+	op   string
+	x, y node
+}
+
+func (b *binary) eval(wm krools.WorkingMemory) (any, error) {
+	x, err := b.x.eval(wm)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.op == "&&" || b.op == "||" {
+		left, err := truth(x)
+		if err != nil {
+			return nil, err
+		}
+
+		if left == (b.op == "||") {
+			return left, nil
+		}
+
+		y, err := b.y.eval(wm)
+		if err != nil {
+			return nil, err
+		}
+
+		return truth(y)
+	}
+
+	y, err := b.y.eval(wm)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case "==":
+		return equal(x, y), nil
+	case "!=":
+		return !equal(x, y), nil
+	case "in":
+		items, ok := y.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a list", y)
+		}
+
+		for _, item := range items {
+			if equal(x, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case "<", "<=", ">", ">=":
+		if x == nil || y == nil {
+			return false, nil
+		}
+
+		c, err := compare(x, y)
+		if err != nil {
+			return nil, err
+		}
+
+		switch b.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	default:
+		return arithmetic(b.op, x, y)
+	}
+}
+
+func (b *binary) staticType() reflect.Type { return nil }
+
+type list struct {
+	items []node
+}
+
+func (l *list) eval(wm krools.WorkingMemory) (any, error) {
+	items := make([]any, 0, len(l.items))
+
+	for _, item := range l.items {
+		v, err := item.eval(wm)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, v)
+	}
+
+	return items, nil
+}
+
+func (l *list) staticType() reflect.Type { return nil }
+
+type call struct {
+	name string
+	fn   func(wm krools.WorkingMemory, args []any, refs []node) (any, error)
+	args []node
+}
+
+func (c *call) eval(wm krools.WorkingMemory) (any, error) {
+	args := make([]any, 0, len(c.args))
+
+	for _, arg := range c.args {
+		if _, ok := arg.(*factRef); ok {
+			args = append(args, nil)
+			continue
+		}
+
+		v, err := arg.eval(wm)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, v)
+	}
+
+	v, err := c.fn(wm, args, c.args)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", c.name, err)
+	}
+
+	return v, nil
+}
+
+func (c *call) staticType() reflect.Type { return nil }
+
+type function struct {
+	args int
+	// types is set if arguments are type names.
+	types bool
+	fn    func(wm krools.WorkingMemory, args []any, refs []node) (any, error)
+}
+
+var functions = map[string]function{
+	"has": {args: 1, types: true, fn: func(wm krools.WorkingMemory, _ []any, refs []node) (any, error) {
+		return !wm.HasNot(refs[0].(*factRef).zero), nil
+	}},
+	"count": {args: 1, types: true, fn: func(wm krools.WorkingMemory, _ []any, refs []node) (any, error) {
+		var n int64
+		for range wm.Facts(refs[0].(*factRef).zero) {
+			n++
+		}
+
+		return n, nil
+	}},
+	"len": {args: 1, fn: func(_ krools.WorkingMemory, args []any, _ []node) (any, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return int64(0), nil
+		case string:
+			return int64(len(v)), nil
+		case []any:
+			return int64(len(v)), nil
+		default:
+			return nil, fmt.Errorf("%v has no length", v)
+		}
+	}},
+	"contains": {args: 2, fn: func(_ krools.WorkingMemory, args []any, _ []node) (any, error) {
+		s, sub, err := strings2(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return strings.Contains(s, sub), nil
+	}},
+	"lower": {args: 1, fn: func(_ krools.WorkingMemory, args []any, _ []node) (any, error) {
+		s, ok := args[0].(string)
+		if !ok && args[0] != nil {
+			return nil, fmt.Errorf("%v is not a string", args[0])
+		}
+
+		return strings.ToLower(s), nil
+	}},
+	"upper": {args: 1, fn: func(_ krools.WorkingMemory, args []any, _ []node) (any, error) {
+		s, ok := args[0].(string)
+		if !ok && args[0] != nil {
+			return nil, fmt.Errorf("%v is not a string", args[0])
+		}
+
+		return strings.ToUpper(s), nil
+	}},
+}
+
+func strings2(args []any) (string, string, error) {
+	a, ok := args[0].(string)
+	if !ok && args[0] != nil {
+		return "", "", fmt.Errorf("%v is not a string", args[0])
+	}
+
+	b, ok := args[1].(string)
+	if !ok && args[1] != nil {
+		return "", "", fmt.Errorf("%v is not a string", args[1])
+	}
+
+	return a, b, nil
+}
+
+// normalize converts numbers to int64 or float64 and named strings and bools to their underlying types.
+func normalize(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if d, ok := v.Interface().(time.Duration); ok {
+			return d.Seconds()
+		}
+
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Slice, reflect.Array:
+		items := make([]any, 0, v.Len())
+		for i := range v.Len() {
+			items = append(items, normalize(v.Index(i)))
+		}
+
+		return items
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return v.Interface()
+	default:
+		return v.Interface()
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func equal(x, y any) bool {
+	if a, ok := toFloat(x); ok {
+		b, ok := toFloat(y)
+		return ok && a == b
+	}
+
+	if a, ok := x.(time.Time); ok {
+		b, ok := y.(time.Time)
+		return ok && a.Equal(b)
+	}
+
+	return reflect.DeepEqual(x, y)
+}
+
+var errNotComparable = errors.New("values are not comparable")
+
+func compare(x, y any) (int, error) {
+	if a, ok := x.(int64); ok {
+		if b, ok := y.(int64); ok {
+			return cmp.Compare(a, b), nil
+		}
+	}
+
+	if a, ok := toFloat(x); ok {
+		if b, ok := toFloat(y); ok {
+			return cmp.Compare(a, b), nil
+		}
+	}
+
+	switch a := x.(type) {
+	case string:
+		if b, ok := y.(string); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case time.Time:
+		if b, ok := y.(time.Time); ok {
+			return a.Compare(b), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %v and %v", errNotComparable, x, y)
+}
+
+func arithmetic(op string, x, y any) (any, error) {
+	if x == nil || y == nil {
+		return nil, nil
+	}
+
+	if a, ok := x.(string); ok && op == "+" {
+		if b, ok := y.(string); ok {
+			return a + b, nil
+		}
+	}
+
+	if a, ok := x.(int64); ok {
+		if b, ok := y.(int64); ok {
+			switch op {
+			case "+":
+				return a + b, nil
+			case "-":
+				return a - b, nil
+			case "*":
+				return a * b, nil
+			case "%":
+				if b == 0 {
+					return nil, errors.New("division by zero")
+				}
+
+				return a % b, nil
+			}
+		}
+	}
+
+	a, aok := toFloat(x)
+	b, bok := toFloat(y)
+
+	if !aok || !bok || op == "%" {
+		return nil, fmt.Errorf("%v %s %v is not supported", x, op, y)
+	}
+
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	default:
+		if b == 0 {
+			return nil, errors.New("division by zero")
+		}
+
+		return a / b, nil
+	}
+}
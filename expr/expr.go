@@ -0,0 +1,160 @@
+// Package expr is a small and safe expression language to write conditions and actions of rules as text, so rules
+// may be changed without recompiling.
+//
+// Expressions refer to facts of working memory set with Set by names of their types registered in Types:
+//
+//	Order.Total >= 100 && Customer.Tier in ["gold", "platinum"] && !has(Discount)
+//
+// A missing fact and its fields are nil. Nil is false for logical operators, it's equal only to nil and ordering
+// comparisons with it are false, so conditions about missing facts are not satisfied. Supported are number, string,
+// bool and nil literals, lists, arithmetic operators + - * / % where / always gives a float, comparisons
+// == != < <= > >=, logical operators && || !, membership in a list with in and functions:
+//
+//	has(T)            a value of type T is set
+//	count(T)          the number of inserted facts of type T
+//	len(v)            the length of a string or a list
+//	contains(s, sub)  the string contains the substring
+//	lower(s), upper(s)
+//
+// Actions are statements:
+//
+//	set T{Field: expression, ...}     sets a new value of type T
+//	modify T{Field: expression, ...}  sets a copy of the value of type T with changed fields
+//	insert T{Field: expression, ...}  inserts a new fact of type T
+//	delete T                          deletes the value of type T
+//	halt                              halts firing
+//
+// Only exported fields of facts may be used. Fields of type time.Duration are numbers of seconds, both when they are
+// read and when they are assigned. Numbers assigned to fields must fit their types.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/krocos/krools/v2"
+)
+
+// Types maps names used by expressions to types of facts.
+type Types struct {
+	types map[string]reflect.Type
+}
+
+func NewTypes() *Types {
+	return &Types{types: make(map[string]reflect.Type)}
+}
+
+// Register registers the type of passed value under the name. The value must be a struct or a pointer to a struct.
+func (t *Types) Register(name string, v any) *Types {
+	typ := reflect.TypeOf(v)
+	if typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("type of %s must be a struct", name))
+	}
+
+	t.types[name] = typ
+
+	return t
+}
+
+func (t *Types) lookup(name string) (reflect.Type, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	typ, ok := t.types[name]
+
+	return typ, ok
+}
+
+// Expression is a compiled expression.
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile compiles the expression, all fact types and their fields it refers to must be known.
+func Compile(src string, types *Types) (*Expression, error) {
+	p, err := newParser(src, types)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.expectEOF(); err != nil {
+		return nil, err
+	}
+
+	return &Expression{src: src, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against working memory.
+func (e *Expression) Eval(wm krools.WorkingMemory) (any, error) {
+	return e.root.eval(wm)
+}
+
+// Condition compiles the expression into a condition, the expression must be evaluated to a bool or nil.
+func Condition(src string, types *Types) (krools.ConditionFn, error) {
+	e, err := Compile(src, types)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx krools.Context) (bool, error) {
+		v, err := e.Eval(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		return truth(v)
+	}, nil
+}
+
+// Action compiles the statements into an action executing them in order.
+func Action(statements []string, types *Types) (krools.ActionFn, error) {
+	compiled := make([]statement, 0, len(statements))
+
+	for i, src := range statements {
+		s, err := compileStatement(src, types)
+		if err != nil {
+			return nil, fmt.Errorf("compile statement %d: %w", i, err)
+		}
+
+		compiled = append(compiled, s)
+	}
+
+	return func(ctx krools.Context) error {
+		for i, s := range compiled {
+			if err := s.exec(ctx); err != nil {
+				return fmt.Errorf("execute statement %d: %w", i, err)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+var errNotBool = errors.New("value is not a bool")
+
+func truth(v any) (bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%w: %v", errNotBool, v)
+	}
+}
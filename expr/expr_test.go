@@ -0,0 +1,242 @@
+package expr_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/expr"
+)
+
+type Tier string
+
+type Customer struct {
+	Name string
+	Tier Tier
+	Tags []string
+}
+
+type Order struct {
+	Total    float64
+	Items    int
+	Customer *Customer
+}
+
+type Discount struct {
+	Percent int
+	Reason  string
+}
+
+type Alert struct {
+	Message string
+}
+
+type Limits struct {
+	Small   int8
+	Count   uint
+	Timeout time.Duration
+}
+
+func types() *expr.Types {
+	return expr.NewTypes().
+		Register("Customer", Customer{}).
+		Register("Order", Order{}).
+		Register("Discount", Discount{}).
+		Register("Alert", &Alert{}).
+		Register("Limits", Limits{})
+}
+
+func TestCompile_Eval(t *testing.T) {
+	s := krools.NewKnowledgeBase("expr base").NewSession()
+	krools.Set(s, Customer{Name: "Ann", Tier: "gold", Tags: []string{"vip"}})
+	krools.Set(s, Order{Total: 150, Items: 3, Customer: &Customer{Name: "Bob"}})
+	krools.Set(s, Limits{Timeout: 45 * time.Second})
+	s.Insert(Alert{Message: "a"})
+	s.Insert(Alert{Message: "b"})
+
+	cases := []struct {
+		src  string
+		want any
+	}{
+		{`1 + 2 * 3`, int64(7)},
+		{`(1 + 2) * 3`, int64(9)},
+		{`7 / 2`, 3.5},
+		{`7 % 2`, int64(1)},
+		{`-Order.Items`, int64(-3)},
+		{`Order.Total > 100 && Order.Items >= 3`, true},
+		{`Order.Total * 0.1`, 15.0},
+		{`Order.Total == 150`, true},
+		{`Customer.Tier in ["gold", "platinum"]`, true},
+		{`Customer.Tier == "silver" || Customer.Name == "Ann"`, true},
+		{`"vip" in Customer.Tags`, true},
+		{`Order.Customer.Name + "!"`, "Bob!"},
+		{`has(Order) && !has(Discount)`, true},
+		{`Discount.Percent`, nil},
+		{`Discount.Percent > 5`, false},
+		{`Discount.Percent == nil`, true},
+		{`!Discount.Percent`, true},
+		{`count(Alert)`, int64(2)},
+		{`len(Customer.Name) == 3 && contains(lower(Customer.Name), "an")`, true},
+		{`upper("a_b") == "A_B"`, true},
+		{`1_000 < 1000.5`, true},
+		{`Limits.Timeout > 30`, true},
+		{`Limits.Timeout * 2`, 90.0},
+	}
+
+	for _, c := range cases {
+		e, err := expr.Compile(c.src, types())
+		if err != nil {
+			t.Errorf("%s: %v", c.src, err)
+			continue
+		}
+
+		got, err := e.Eval(s)
+		if err != nil {
+			t.Errorf("%s: %v", c.src, err)
+			continue
+		}
+
+		if got != c.want {
+			t.Errorf("%s: expected %v (%T), got %v (%T)", c.src, c.want, c.want, got, got)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for src, msg := range map[string]string{
+		`Order.Totl > 1`:          "no exported field Totl",
+		`Invoice.Total`:           "unknown type 'Invoice'",
+		`1 +`:                     "unexpected end of expression",
+		`(1 + 2`:                  "expected ')'",
+		`has(1)`:                  "expected type name",
+		`len("a", "b")`:           "expects 1 argument(s)",
+		`sqrt(4)`:                 "unknown function 'sqrt'",
+		`1 2`:                     "unexpected '2'",
+		`"abc`:                    "unterminated string",
+		`Order.Total # 2`:         "unexpected character '#'",
+		`Order.Customer.Nickname`: "no exported field Nickname",
+	} {
+		_, err := expr.Compile(src, types())
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected error with %q, got %v", src, msg, err)
+		}
+	}
+}
+
+func TestConditionAction(t *testing.T) {
+	when, err := expr.Condition(`Order.Total >= 100 && !has(Discount)`, types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	then, err := expr.Action([]string{
+		`set Discount{Percent: Order.Total / 10, Reason: "large order " + Customer.Name}`,
+		`modify Order{Items: Order.Items + 1}`,
+		`insert Alert{Message: "discounted"}`,
+		`delete Customer`,
+	}, types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := krools.NewKnowledgeBase("expr base").
+		Add(krools.NewInlineRule("discount", when, then)).
+		NewSession()
+
+	krools.Set(s, Order{Total: 150, Items: 1})
+	krools.Set(s, Customer{Name: "Ann"})
+
+	if err = s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := krools.MustGet[Discount](s); d.Percent != 15 || d.Reason != "large order Ann" {
+		t.Errorf("unexpected discount: %+v", d)
+	}
+
+	if o := krools.MustGet[Order](s); o.Items != 2 || o.Total != 150 {
+		t.Errorf("unexpected order: %+v", o)
+	}
+
+	if krools.Has[Customer](s) {
+		t.Error("customer is not deleted")
+	}
+
+	var alerts int
+	for range s.Facts(Alert{}) {
+		alerts++
+	}
+
+	if alerts != 1 {
+		t.Errorf("expected 1 alert, got %d", alerts)
+	}
+}
+
+func TestAction_Errors(t *testing.T) {
+	for src, msg := range map[string]string{
+		`set Discount{Percnt: 1}`:         "no exported field Percnt",
+		`update Discount{}`:               "unknown statement 'update'",
+		`delete Discount{}`:               "unexpected '{'",
+		`set Invoice{}`:                   "expected type name",
+		`set Discount{Percent: 1 Reason}`: "expected ','",
+	} {
+		_, err := expr.Action([]string{src}, types())
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected error with %q, got %v", src, msg, err)
+		}
+	}
+
+	then, err := expr.Action([]string{`set Discount{Percent: 1.5}`}, types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := krools.NewKnowledgeBase("expr base").
+		Add(krools.NewInlineRule("fractional", func(ctx krools.Context) (bool, error) { return true, nil }, then)).
+		NewSession()
+
+	if err = s.FireAllRules(context.Background()); err == nil || !strings.Contains(err.Error(), "not an integer") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAction_Conversions(t *testing.T) {
+	fire := func(src string) (Limits, error) {
+		then, err := expr.Action([]string{src}, types())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := krools.NewKnowledgeBase("expr base").
+			Add(krools.NewInlineRule("convert", nil, then).Deactivate()).
+			NewSession()
+
+		err = s.FireAllRules(context.Background())
+		l, _ := krools.Get[Limits](s)
+
+		return l, err
+	}
+
+	for src, msg := range map[string]string{
+		`set Limits{Small: 300}`:                     "300 overflows int8",
+		`set Limits{Count: -1}`:                      "-1 overflows uint",
+		`set Limits{Timeout: "1s"}`:                  "is not a number of seconds",
+		`set Limits{Small: 100000000000000000000.0}`: "overflows int8",
+		`set Limits{Timeout: 100000000000}`:          "overflow time.Duration",
+	} {
+		if _, err := fire(src); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected error with %q, got %v", src, msg, err)
+		}
+	}
+
+	l, err := fire(`set Limits{Small: -128, Count: 7, Timeout: 1.5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (Limits{Small: -128, Count: 7, Timeout: 1500 * time.Millisecond}); l != want {
+		t.Errorf("expected %+v, got %+v", want, l)
+	}
+}
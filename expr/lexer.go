@@ -0,0 +1,100 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+
+	return fmt.Sprintf("'%s' at %d", t.text, t.pos)
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "(", ")", "{", "}", "[", "]", ",", ":", ".", "!", "<", ">", "+", "-", "*", "/", "%"}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	for pos := 0; pos < len(src); {
+		c := rune(src[pos])
+
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '_' || unicode.IsLetter(c):
+			end := pos
+			for end < len(src) && (src[end] == '_' || unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end]))) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: src[pos:end], pos: pos})
+			pos = end
+		case unicode.IsDigit(c):
+			end := pos
+			for end < len(src) && (unicode.IsDigit(rune(src[end])) || src[end] == '.' || src[end] == '_') {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: src[pos:end], pos: pos})
+			pos = end
+		case c == '"':
+			end := pos + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", pos)
+			}
+
+			s, err := strconv.Unquote(src[pos : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", pos, err)
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: s, pos: pos})
+			pos = end + 1
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[pos:], o) {
+					op = o
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at %d", c, pos)
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
@@ -0,0 +1,332 @@
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+	types  *Types
+}
+
+func newParser(src string, types *Types) (*parser, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens, types: types}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) is(text string) bool {
+	t := p.peek()
+
+	return (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if t := p.next(); t.text != text || t.kind == tokenString {
+		return fmt.Errorf("expected '%s', got %s", text, t)
+	}
+
+	return nil
+}
+
+func (p *parser) expectEOF() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return fmt.Errorf("unexpected %s", t)
+	}
+
+	return nil
+}
+
+// binaryLevels are binary operators from the lowest precedence to the highest one.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseExpression() (node, error) {
+	return p.parseBinary(0)
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+
+	x, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+		for _, o := range binaryLevels[level] {
+			if p.is(o) {
+				op = o
+				break
+			}
+		}
+
+		if op == "" {
+			return x, nil
+		}
+
+		p.next()
+
+		y, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		x = &binary{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.is("!") || p.is("-") {
+		op := p.next().text
+
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unary{op: op, x: x}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is(".") {
+		p.next()
+
+		t := p.next()
+		if t.kind != tokenIdent {
+			return nil, fmt.Errorf("expected field name, got %s", t)
+		}
+
+		s := &selector{x: x, field: t.text}
+
+		if typ := structType(x.staticType()); typ != nil {
+			f, ok := typ.FieldByName(t.text)
+			if !ok || !f.IsExported() {
+				return nil, fmt.Errorf("type %s has no exported field %s", typ, t.text)
+			}
+
+			s.typ = f.Type
+		}
+
+		x = s
+	}
+
+	return x, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenNumber:
+		text := strings.ReplaceAll(t.text, "_", "")
+
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return &literal{v: i}, nil
+		}
+
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t)
+		}
+
+		return &literal{v: f}, nil
+	case tokenString:
+		return &literal{v: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literal{v: true}, nil
+		case "false":
+			return &literal{v: false}, nil
+		case "nil":
+			return &literal{v: nil}, nil
+		}
+
+		if p.is("(") {
+			return p.parseCall(t)
+		}
+
+		typ, ok := p.types.lookup(t.text)
+		if !ok {
+			return nil, fmt.Errorf("unknown type %s", t)
+		}
+
+		return newFactRef(typ), nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			x, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+
+			return x, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %s", t)
+}
+
+func (p *parser) parseList() (node, error) {
+	l := &list{}
+
+	for !p.is("]") {
+		item, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		l.items = append(l.items, item)
+
+		if !p.is("]") {
+			if err = p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.next()
+
+	return l, nil
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	p.next()
+
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+
+	c := &call{name: name.text, fn: fn.fn}
+
+	for !p.is(")") {
+		var (
+			arg node
+			err error
+		)
+
+		if fn.types {
+			t := p.next()
+
+			typ, ok := p.types.lookup(t.text)
+			if t.kind != tokenIdent || !ok {
+				return nil, fmt.Errorf("expected type name, got %s", t)
+			}
+
+			arg = newFactRef(typ)
+		} else if arg, err = p.parseExpression(); err != nil {
+			return nil, err
+		}
+
+		c.args = append(c.args, arg)
+
+		if !p.is(")") {
+			if err = p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.next()
+
+	if len(c.args) != fn.args {
+		return nil, fmt.Errorf("function %s expects %d argument(s), got %d", name, fn.args, len(c.args))
+	}
+
+	return c, nil
+}
+
+// parseFields parses a struct literal after the type name: {Field: expression, ...}.
+func (p *parser) parseFields(typ reflect.Type) ([]field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var fields []field
+
+	for !p.is("}") {
+		t := p.next()
+		if t.kind != tokenIdent {
+			return nil, fmt.Errorf("expected field name, got %s", t)
+		}
+
+		f, ok := typ.FieldByName(t.text)
+		if !ok || !f.IsExported() {
+			return nil, fmt.Errorf("type %s has no exported field %s", typ, t.text)
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		v, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, field{index: f.Index, name: f.Name, typ: f.Type, v: v})
+
+		if !p.is("}") {
+			if err = p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.next()
+
+	return fields, nil
+}
+
+func structType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return t
+}
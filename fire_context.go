@@ -2,34 +2,215 @@ package krools
 
 import (
 	"context"
+	"iter"
+	"log/slog"
+	"time"
 )
 
 type fireContext struct {
 	ctx context.Context
 	*structTypeContainer
-	rule *RuleHandle
+	rule   *RuleHandle
+	rete   *reteMemory
+	tms    *truthMaintenance
+	expiry *eventExpiry
+	clock  Clock
+
+	logger            *slog.Logger
+	knowledgeBaseName string
+
+	// locals keeps locals of rules until their actions are executed.
+	locals map[*RuleHandle]*structTypeContainer
+
+	stats   Stats
+	metrics *fireMetrics
+	halted  bool
+
+	// supports keeps what satisfied conditions of rules depended on, so actions can set values logically.
+	supports map[*RuleHandle]support
+
+	// reads collects type keys read by the condition being evaluated, it's nil outside of conditions.
+	reads map[string]struct{}
+	// volatile is set when the condition being evaluated depends on something besides working memory.
+	volatile bool
+	// acting is set while an action is executed, values handed out to actions as pointers are considered as changed
+	// since actions may change them, values read as copies are not.
+	acting bool
 }
 
 func (f *fireContext) Context() context.Context {
+	f.volatile = true
+
 	return f.ctx
 }
 
+// Logger returns the logger of the session with names of the rule and the knowledge base attached.
+func (f *fireContext) Logger() *slog.Logger {
+	l := f.logger.With(slog.String("knowledge_base", f.knowledgeBaseName))
+	if f.rule != nil {
+		l = l.With(slog.String("rule", f.rule.name))
+	}
+
+	return l
+}
+
+func (f *fireContext) Halt() {
+	f.halted = true
+}
+
+func (f *fireContext) Set(v any) {
+	f.setKey(structValue(v))
+}
+
+// SetLogical sets value justified by the condition of the rule being executed. The value is deleted automatically
+// once the condition is not satisfied anymore by facts it read, unless another rule justifies it too or the value is
+// set again with Set.
+func (f *fireContext) SetLogical(v any) {
+	key, v := structValue(v)
+
+	f.structTypeContainer.setKey(key, v)
+	f.tms.justify(key, newJustification(key, f.rule, f.supports[f.rule], f.structTypeContainer))
+}
+
+func (f *fireContext) Get(v any) bool {
+	f.read(structKey(v))
+
+	return f.structTypeContainer.Get(v)
+}
+
+func (f *fireContext) Handle(v any) any {
+	return f.handleKey(structKey(v))
+}
+
+func (f *fireContext) HasNot(v any) bool {
+	f.read(structKey(v))
+
+	return f.structTypeContainer.HasNot(v)
+}
+
+func (f *fireContext) Fact(h FactHandle) any {
+	if key, ok := f.factKey(h); ok {
+		f.handOutFacts(key, h)
+	}
+
+	return f.structTypeContainer.Fact(h)
+}
+
+func (f *fireContext) Facts(v any) iter.Seq2[FactHandle, any] {
+	key := structKey(v)
+	f.handOutFacts(key, 0)
+
+	return f.factsKey(key)
+}
+
+func (f *fireContext) setKey(key string, v any) {
+	f.tms.unjustify(key)
+	f.structTypeContainer.setKey(key, v)
+}
+
+func (f *fireContext) EventTime(h FactHandle) (EventTime, bool) {
+	if key, ok := f.factKey(h); ok {
+		f.readFacts(key)
+	}
+
+	return f.structTypeContainer.EventTime(h)
+}
+
+func (f *fireContext) eventsKey(key string) []event {
+	f.handOutFacts(key, 0)
+
+	return f.structTypeContainer.eventsKey(key)
+}
+
+// Clock returns the clock of the session, conditions asking for it depend on time.
+func (f *fireContext) Clock() Clock {
+	f.volatile = true
+
+	return f.clock
+}
+
+func (f *fireContext) now() time.Time {
+	return f.Clock().Now()
+}
+
+func (f *fireContext) window(key string, d time.Duration) {
+	f.expiry.window(key, d)
+}
+
+func (f *fireContext) getKey(key string) any {
+	f.read(key)
+
+	return f.structTypeContainer.getKey(key)
+}
+
+func (f *fireContext) handleKey(key string) any {
+	f.handOut(key)
+
+	return f.structTypeContainer.handleKey(key)
+}
+
 func (f *fireContext) SetLocal(v any) {
-	f.rule.locals.Set(v)
+	f.volatile = true
+	f.local().Set(v)
 }
 
 func (f *fireContext) GetLocal(v any) bool {
-	return f.rule.locals.Get(v)
+	f.volatile = true
+	return f.local().Get(v)
 }
 
 func (f *fireContext) LocalHandle(v any) any {
-	return f.rule.locals.Handle(v)
+	f.volatile = true
+	return f.local().Handle(v)
 }
 
 func (f *fireContext) HasNotLocal(v any) bool {
-	return f.rule.locals.HasNot(v)
+	f.volatile = true
+	return f.local().HasNot(v)
 }
 
 func (f *fireContext) DeleteLocal(v any) {
-	f.rule.locals.Delete(v)
+	f.volatile = true
+	f.local().Delete(v)
+}
+
+func (f *fireContext) local() *structTypeContainer {
+	c, ok := f.locals[f.rule]
+	if !ok {
+		c = newStructTypeContainer()
+		f.locals[f.rule] = c
+	}
+
+	return c
+}
+
+// read records the type key read by the condition being evaluated.
+func (f *fireContext) read(key string) {
+	if f.reads != nil {
+		f.reads[key] = struct{}{}
+	}
+}
+
+// handOut is like read but for pointers to values handed out, an action may change values through them, so they are
+// taken as changed.
+func (f *fireContext) handOut(key string) {
+	f.read(key)
+
+	if f.reads == nil && f.acting {
+		f.touch(key)
+	}
+}
+
+// readFacts is like read but for inserted facts.
+func (f *fireContext) readFacts(key string) {
+	f.read(key)
+}
+
+// handOutFacts is like handOut but for inserted facts, the handle is zero when all facts of the type are handed out.
+func (f *fireContext) handOutFacts(key string, h FactHandle) {
+	f.read(key)
+
+	if f.reads == nil && f.acting {
+		f.changed(key, h)
+	}
 }
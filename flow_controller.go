@@ -5,6 +5,7 @@ const preStartPos int = -1
 type flowController struct {
 	ret        *retracting
 	units      map[string][]*RuleHandle
+	ruleNames  map[string][]string
 	unitsOrder []string
 
 	pos      int
@@ -14,17 +15,19 @@ type flowController struct {
 func newFlowController(
 	ret *retracting,
 	units map[string][]*RuleHandle,
+	ruleNames map[string][]string,
 	unitsOrder []string,
 	deactivatedUnits []string,
 ) *flowController {
 	c := &flowController{
 		ret:        ret,
 		units:      units,
+		ruleNames:  ruleNames,
 		unitsOrder: unitsOrder,
 		pos:        preStartPos,
 	}
 
-	c.deactivateUnits(deactivatedUnits...)
+	c.deactivateUnits("", deactivatedUnits...)
 
 	return c
 }
@@ -33,17 +36,18 @@ func (c *flowController) activateUnits(units ...string) {
 	c.ret.reject(c.unitsRuleNames(units...)...)
 }
 
-func (c *flowController) deactivateUnits(units ...string) {
-	c.ret.add(c.unitsRuleNames(units...)...)
+// deactivateUnits retracts rules of the units, by is the name of the rule deactivating them.
+func (c *flowController) deactivateUnits(by string, units ...string) {
+	for _, u := range units {
+		c.ret.add(retraction{by: by, unit: u}, c.unitsRuleNames(u)...)
+	}
 }
 
 func (c *flowController) unitsRuleNames(units ...string) []string {
 	var ruleNames []string
 
 	for _, u := range units {
-		for _, r := range c.units[u] {
-			ruleNames = append(ruleNames, r.name)
-		}
+		ruleNames = append(ruleNames, c.ruleNames[u]...)
 	}
 
 	return ruleNames
@@ -57,6 +61,10 @@ func (c *flowController) setFocus(units ...string) {
 	}
 }
 
+func (c *flowController) unit() string {
+	return c.unitsOrder[c.pos]
+}
+
 func (c *flowController) rules() []*RuleHandle {
 	return c.units[c.unitsOrder[c.pos]]
 }
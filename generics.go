@@ -0,0 +1,123 @@
+package krools
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"sync"
+)
+
+// WorkingMemory gives access to facts of a session. It's implemented by Context and Session.
+type WorkingMemory interface {
+	Set(v any)
+	Get(v any) bool
+	Handle(v any) any
+	HasNot(v any) bool
+	Delete(v any)
+
+	Insert(v any) FactHandle
+	Update(h FactHandle, v any) bool
+	Retract(h FactHandle) bool
+	Fact(h FactHandle) any
+	Facts(v any) iter.Seq2[FactHandle, any]
+
+	InsertEvent(v any, t EventTime) FactHandle
+	EventTime(h FactHandle) (EventTime, bool)
+}
+
+// keyedMemory is implemented by working memories of the package and allows to skip reflection over passed values.
+type keyedMemory interface {
+	// getKey is like handleKey but the value is only read, so it's not marked as changed.
+	getKey(key string) any
+	handleKey(key string) any
+	setKey(key string, v any)
+	deleteKey(key string)
+}
+
+var typeKeys sync.Map
+
+func keyOf[T any]() string {
+	t := reflect.TypeFor[T]()
+
+	if n, ok := typeKeys.Load(t); ok {
+		return n.(string)
+	}
+
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("%s must be a struct", t))
+	}
+
+	n := typeKey(t)
+	typeKeys.Store(t, n)
+
+	return n
+}
+
+// Get returns a copy of the value of type T and true if such value exists in working memory.
+func Get[T any](m WorkingMemory) (T, bool) {
+	if v := get[T](m); v != nil {
+		return *v, true
+	}
+
+	var zero T
+
+	return zero, false
+}
+
+// MustGet returns a copy of the value of type T and panics if there is no such value in working memory.
+func MustGet[T any](m WorkingMemory) T {
+	v := get[T](m)
+	if v == nil {
+		panic(fmt.Sprintf("there is no %s in working memory", reflect.TypeFor[T]()))
+	}
+
+	return *v
+}
+
+// get returns the value of type T stored in working memory to read it only.
+func get[T any](m WorkingMemory) *T {
+	if km, ok := m.(keyedMemory); ok {
+		v, _ := km.getKey(keyOf[T]()).(*T)
+		return v
+	}
+
+	return Handle[T](m)
+}
+
+// Handle returns a pointer to the value of type T stored in working memory or nil if there is no such value. Actions
+// may change the value through the pointer, so the value is taken as changed.
+func Handle[T any](m WorkingMemory) *T {
+	if km, ok := m.(keyedMemory); ok {
+		v, _ := km.handleKey(keyOf[T]()).(*T)
+		return v
+	}
+
+	v, _ := m.Handle((*T)(nil)).(*T)
+
+	return v
+}
+
+// Set sets a copy of passed value in working memory.
+func Set[T any](m WorkingMemory, v T) {
+	if km, ok := m.(keyedMemory); ok {
+		km.setKey(keyOf[T](), &v)
+		return
+	}
+
+	m.Set(&v)
+}
+
+// Has checks if there is a value of type T in working memory.
+func Has[T any](m WorkingMemory) bool {
+	return get[T](m) != nil
+}
+
+// Delete deletes the value of type T from working memory.
+func Delete[T any](m WorkingMemory) {
+	if km, ok := m.(keyedMemory); ok {
+		km.deleteKey(keyOf[T]())
+		return
+	}
+
+	m.Delete((*T)(nil))
+}
@@ -0,0 +1,63 @@
+package krools_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Discount struct {
+	percent int
+}
+
+func TestGenerics(t *testing.T) {
+	k := krools.NewKnowledgeBase("generics base")
+	k.Add(krools.NewInlineRule("discount for large order", func(ctx krools.Context) (bool, error) {
+		o, ok := krools.Get[Order](ctx)
+		return ok && o.total > 100 && !krools.Has[Discount](ctx), nil
+	}, func(ctx krools.Context) error {
+		krools.Set(ctx, Discount{percent: 10})
+		krools.Handle[Order](ctx).total -= 10
+
+		return nil
+	}))
+
+	s := k.NewSession()
+	krools.Set(s, Order{id: 1, total: 200})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := krools.MustGet[Discount](s); d.percent != 10 {
+		t.Fatalf("unexpected discount: %d", d.percent)
+	}
+
+	if o := krools.MustGet[Order](s); o.total != 190 {
+		t.Fatalf("unexpected total: %d", o.total)
+	}
+
+	krools.Delete[Discount](s)
+	if _, ok := krools.Get[Discount](s); ok {
+		t.Fatal("discount is not deleted")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("must panic")
+		}
+	}()
+	krools.MustGet[Discount](s)
+}
+
+func TestGenerics_NonStruct(t *testing.T) {
+	s := krools.NewKnowledgeBase("generics base").NewSession()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("must panic")
+		}
+	}()
+	krools.Has[int](s)
+}
@@ -0,0 +1,335 @@
+package krools
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// edgeKind is a way a rule changes the flow of rules.
+type edgeKind int
+
+const (
+	edgeActivationUnit edgeKind = iota
+	edgeDeactivate
+	edgeActivate
+	edgeDeactivateUnit
+	edgeActivateUnit
+	edgeFocus
+)
+
+func (k edgeKind) String() string {
+	switch k {
+	case edgeActivationUnit:
+		return "activation unit"
+	case edgeDeactivate:
+		return "deactivate"
+	case edgeActivate:
+		return "activate"
+	case edgeDeactivateUnit:
+		return "deactivate unit"
+	case edgeActivateUnit:
+		return "activate unit"
+	case edgeFocus:
+		return "focus"
+	default:
+		return "unknown"
+	}
+}
+
+type graphNode struct {
+	id    string
+	label []string
+}
+
+type graphUnit struct {
+	graphNode
+	deactivated bool
+	rules       []graphNode
+}
+
+type graphEdge struct {
+	from, to string
+	kind     edgeKind
+}
+
+// graph is the flow of rules of a knowledge base. Nodes are numbered in the order rules were added, so the output
+// doesn't change while rules don't. Rules and units referenced but not added are missing nodes.
+type graph struct {
+	name            string
+	units           []*graphUnit
+	activationUnits []graphNode
+	missing         []graphNode
+	edges           []graphEdge
+}
+
+func (k *KnowledgeBase) graph() *graph {
+	g := &graph{name: k.name}
+
+	ruleIDs := make(map[string][]string)
+	unitIDs := make(map[string]string)
+	rules := 0
+
+	for i, unit := range k.unitsOrder {
+		u := &graphUnit{
+			graphNode:   graphNode{id: fmt.Sprintf("u%d", i), label: []string{unit}},
+			deactivated: contains(k.deactivatedUnits, unit),
+		}
+
+		for _, rule := range k.units[unit] {
+			id := fmt.Sprintf("r%d", rules)
+			rules++
+
+			ruleIDs[rule.name] = append(ruleIDs[rule.name], id)
+			u.rules = append(u.rules, graphNode{id: id, label: rule.labels()})
+		}
+
+		unitIDs[unit] = u.id
+		g.units = append(g.units, u)
+	}
+
+	missing := func(name string) string {
+		id := fmt.Sprintf("m%d", len(g.missing))
+		g.missing = append(g.missing, graphNode{id: id, label: []string{name}})
+
+		return id
+	}
+
+	rulesByName := func(name string) []string {
+		if _, ok := ruleIDs[name]; !ok {
+			ruleIDs[name] = []string{missing(name)}
+		}
+
+		return ruleIDs[name]
+	}
+
+	unitByName := func(name string) string {
+		if _, ok := unitIDs[name]; !ok {
+			unitIDs[name] = missing(name)
+		}
+
+		return unitIDs[name]
+	}
+
+	activationUnitIDs := make(map[string]string)
+
+	for i, unit := range k.unitsOrder {
+		for j, rule := range k.units[unit] {
+			from := g.units[i].rules[j].id
+
+			if rule.activationUnit != nil {
+				name := *rule.activationUnit
+				if _, ok := activationUnitIDs[name]; !ok {
+					activationUnitIDs[name] = fmt.Sprintf("a%d", len(g.activationUnits))
+					g.activationUnits = append(g.activationUnits, graphNode{id: activationUnitIDs[name], label: []string{name}})
+				}
+
+				g.edges = append(g.edges, graphEdge{from: from, to: activationUnitIDs[name], kind: edgeActivationUnit})
+			}
+
+			for _, refs := range []struct {
+				kind  edgeKind
+				names []string
+			}{
+				{edgeDeactivate, rule.retracts},
+				{edgeActivate, rule.inserts},
+			} {
+				for _, name := range refs.names {
+					for _, to := range rulesByName(name) {
+						g.edges = append(g.edges, graphEdge{from: from, to: to, kind: refs.kind})
+					}
+				}
+			}
+
+			for _, refs := range []struct {
+				kind  edgeKind
+				names []string
+			}{
+				{edgeDeactivateUnit, rule.deactivateUnits},
+				{edgeActivateUnit, rule.activateUnits},
+				{edgeFocus, rule.focusUnits},
+			} {
+				for _, name := range refs.names {
+					g.edges = append(g.edges, graphEdge{from: from, to: unitByName(name), kind: refs.kind})
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// labels describes the rule in a node of a graph: its name and attributes affecting the order of execution.
+func (r *RuleHandle) labels() []string {
+	labels := []string{r.name}
+
+	if r.salience != 0 {
+		labels = append(labels, fmt.Sprintf("salience %d", r.salience))
+	}
+
+	if r.noLoop {
+		labels = append(labels, "no-loop")
+	}
+
+	if r.timer != nil {
+		if r.timer.cron != nil {
+			labels = append(labels, "cron")
+		} else {
+			labels = append(labels, fmt.Sprintf("timer %s", r.timer.delay))
+		}
+	}
+
+	return labels
+}
+
+// WriteDOT writes the flow of rules of the knowledge base as a Graphviz DOT digraph. Units are clusters of their
+// rules, dashed if deactivated from the start, activation units are diamonds linked to their rules and rules or
+// units referenced but not added are red dashed nodes. Edges are activations, deactivations and focus changes made
+// by rules.
+func (k *KnowledgeBase) WriteDOT(w io.Writer) error {
+	k.mu.Lock()
+	g := k.graph()
+	k.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.name))
+	b.WriteString("\tcompound=true;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+
+	firstRule := make(map[string]string)
+
+	for _, u := range g.units {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%s {\n", u.id)
+		fmt.Fprintf(&b, "\t\tlabel=%s;\n", dotLabel(u.label))
+		if u.deactivated {
+			b.WriteString("\t\tstyle=dashed;\n")
+		}
+
+		for _, r := range u.rules {
+			fmt.Fprintf(&b, "\t\t%s [label=%s];\n", r.id, dotLabel(r.label))
+		}
+
+		b.WriteString("\t}\n")
+
+		firstRule[u.id] = u.rules[0].id
+	}
+
+	for _, a := range g.activationUnits {
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=diamond, style=solid];\n", a.id, dotLabel(a.label))
+	}
+
+	for _, m := range g.missing {
+		fmt.Fprintf(&b, "\t%s [label=%s, color=red, style=dashed];\n", m.id, dotLabel(m.label))
+	}
+
+	for _, e := range g.edges {
+		attrs := []string{"label=" + dotQuote(e.kind.String())}
+
+		switch e.kind {
+		case edgeActivationUnit:
+			attrs = []string{"style=dotted", "dir=none"}
+		case edgeDeactivate, edgeDeactivateUnit:
+			attrs = append(attrs, "color=red", "style=dashed")
+		case edgeActivate, edgeActivateUnit:
+			attrs = append(attrs, "color=darkgreen")
+		case edgeFocus:
+			attrs = append(attrs, "color=blue", "style=bold")
+		}
+
+		to := e.to
+		if rule, ok := firstRule[e.to]; ok {
+			to = rule
+			attrs = append(attrs, "lhead=cluster_"+e.to)
+		}
+
+		fmt.Fprintf(&b, "\t%s -> %s [%s];\n", e.from, to, strings.Join(attrs, ", "))
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// WriteMermaid writes the flow of rules of the knowledge base as a Mermaid flowchart. Units are subgraphs of their
+// rules, dashed if deactivated from the start, activation units are hexagons linked to their rules and rules or
+// units referenced but not added are nodes of the missing class. Edges are activations, deactivations and focus
+// changes made by rules.
+func (k *KnowledgeBase) WriteMermaid(w io.Writer) error {
+	k.mu.Lock()
+	g := k.graph()
+	k.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	for _, u := range g.units {
+		fmt.Fprintf(&b, "\tsubgraph %s[%s]\n", u.id, mermaidLabel(u.label))
+
+		for _, r := range u.rules {
+			fmt.Fprintf(&b, "\t\t%s(%s)\n", r.id, mermaidLabel(r.label))
+		}
+
+		b.WriteString("\tend\n")
+	}
+
+	for _, a := range g.activationUnits {
+		fmt.Fprintf(&b, "\t%s{{%s}}\n", a.id, mermaidLabel(a.label))
+	}
+
+	for _, m := range g.missing {
+		fmt.Fprintf(&b, "\t%s[%s]:::missing\n", m.id, mermaidLabel(m.label))
+	}
+
+	for _, e := range g.edges {
+		switch e.kind {
+		case edgeActivationUnit:
+			fmt.Fprintf(&b, "\t%s -.- %s\n", e.from, e.to)
+		case edgeDeactivate, edgeDeactivateUnit:
+			fmt.Fprintf(&b, "\t%s -.->|%s| %s\n", e.from, e.kind, e.to)
+		case edgeActivate, edgeActivateUnit:
+			fmt.Fprintf(&b, "\t%s -->|%s| %s\n", e.from, e.kind, e.to)
+		case edgeFocus:
+			fmt.Fprintf(&b, "\t%s ==>|%s| %s\n", e.from, e.kind, e.to)
+		}
+	}
+
+	if len(g.missing) > 0 {
+		b.WriteString("\tclassDef missing stroke:red,stroke-dasharray:5 5\n")
+	}
+
+	for _, u := range g.units {
+		if u.deactivated {
+			fmt.Fprintf(&b, "\tstyle %s stroke-dasharray:5 5\n", u.id)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func dotLabel(lines []string) string {
+	quoted := make([]string, len(lines))
+	for i, line := range lines {
+		quoted[i] = strings.TrimSuffix(strings.TrimPrefix(dotQuote(line), `"`), `"`)
+	}
+
+	return `"` + strings.Join(quoted, `\n`) + `"`
+}
+
+func mermaidLabel(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(line)
+	}
+
+	return `"` + strings.Join(escaped, "<br/>") + `"`
+}
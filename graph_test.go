@@ -0,0 +1,86 @@
+package krools_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+func graphKnowledgeBase() *krools.KnowledgeBase {
+	return krools.NewKnowledgeBase("orders").
+		Add(krools.NewInlineRule("start", nil, noop).Salience(10).Deactivate().ActivateUnits("checks").SetFocus("checks")).
+		Add(krools.NewInlineRule(`check "a"`, nil, noop).Unit("checks").ActivationUnit("one").Activate("gone")).
+		Add(krools.NewInlineRule("check b", nil, noop).Unit("checks").ActivationUnit("one").NoLoop().DeactivateUnits("nowhere")).
+		SetDeactivatedUnits("checks")
+}
+
+func TestKnowledgeBase_WriteDOT(t *testing.T) {
+	var b strings.Builder
+	if err := graphKnowledgeBase().WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph "orders" {
+	compound=true;
+	node [shape=box, style=rounded];
+	subgraph cluster_u0 {
+		label="MAIN";
+		r0 [label="start\nsalience 10"];
+	}
+	subgraph cluster_u1 {
+		label="checks";
+		style=dashed;
+		r1 [label="check \"a\""];
+		r2 [label="check b\nno-loop"];
+	}
+	a0 [label="one", shape=diamond, style=solid];
+	m0 [label="gone", color=red, style=dashed];
+	m1 [label="nowhere", color=red, style=dashed];
+	r0 -> r0 [label="deactivate", color=red, style=dashed];
+	r0 -> r1 [label="activate unit", color=darkgreen, lhead=cluster_u1];
+	r0 -> r1 [label="focus", color=blue, style=bold, lhead=cluster_u1];
+	r1 -> a0 [style=dotted, dir=none];
+	r1 -> m0 [label="activate", color=darkgreen];
+	r2 -> a0 [style=dotted, dir=none];
+	r2 -> m1 [label="deactivate unit", color=red, style=dashed];
+}
+`
+
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestKnowledgeBase_WriteMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := graphKnowledgeBase().WriteMermaid(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `flowchart LR
+	subgraph u0["MAIN"]
+		r0("start<br/>salience 10")
+	end
+	subgraph u1["checks"]
+		r1("check #quot;a#quot;")
+		r2("check b<br/>no-loop")
+	end
+	a0{{"one"}}
+	m0["gone"]:::missing
+	m1["nowhere"]:::missing
+	r0 -.->|deactivate| r0
+	r0 -->|activate unit| u1
+	r0 ==>|focus| u1
+	r1 -.- a0
+	r1 -->|activate| m0
+	r2 -.- a0
+	r2 -.->|deactivate unit| m1
+	classDef missing stroke:red,stroke-dasharray:5 5
+	style u1 stroke-dasharray:5 5
+`
+
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
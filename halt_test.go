@@ -0,0 +1,100 @@
+package krools_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+func TestFireUntilHalt(t *testing.T) {
+	s := ticketsBase().NewSession()
+
+	done := make(chan error)
+	go func() { done <- s.FireUntilHalt(context.Background()) }()
+
+	for i := range 100 {
+		s.Insert(Ticket{n: i})
+	}
+
+	deadline := time.After(5 * time.Second)
+
+	for {
+		if c, ok := krools.Get[Counter](s); ok && c.n == 100 {
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("tickets are not counted")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	s.Halt()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFireUntilHalt_HaltFromAction(t *testing.T) {
+	k := ticketsBase().
+		Add(krools.NewInlineRule("halt on enough tickets", func(ctx krools.Context) (bool, error) {
+			c, ok := krools.Get[Counter](ctx)
+			return ok && c.n >= 3, nil
+		}, func(ctx krools.Context) error {
+			ctx.Halt()
+			return nil
+		}))
+
+	s := k.NewSession()
+
+	done := make(chan error)
+	go func() { done <- s.FireUntilHalt(context.Background()) }()
+
+	for i := range 3 {
+		s.Insert(Ticket{n: i})
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("session is not halted")
+	}
+}
+
+func TestFireUntilHalt_Cancel(t *testing.T) {
+	s := ticketsBase().NewSession()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() { done <- s.FireUntilHalt(ctx) }()
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHalt_Idle(t *testing.T) {
+	s := ticketsBase().NewSession()
+	s.Halt()
+
+	s.Insert(Ticket{n: 1})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, ok := krools.Get[Counter](s); !ok || c.n != 1 {
+		t.Fatalf("expected the ticket to be counted after an idle halt, got %v", c)
+	}
+}
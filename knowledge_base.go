@@ -1,11 +1,51 @@
 package krools
 
+import (
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+)
+
 type KnowledgeBase struct {
 	name             string
 	units            map[string][]*RuleHandle
 	unitsOrder       []string
 	activationUnits  map[string][]*RuleHandle
 	deactivatedUnits []string
+
+	expirations map[string]time.Duration
+
+	mu        sync.Mutex
+	network   *rete
+	resolver  ConflictResolver
+	stateless *snapshot
+	listeners *listeners
+	tracer    Tracer
+	logger    *slog.Logger
+
+	metricsExporter MetricsExporter
+
+	// statelessRevision is the revision of rules the stateless snapshot is made at.
+	statelessRevision uint64
+
+	// built is set for knowledge bases made by Build, they are never changed.
+	built bool
+}
+
+// snapshot is a copy of rules of a knowledge base shared by stateless sessions. Sessions never modify rules, so it's
+// safe to share it between goroutines.
+type snapshot struct {
+	units            map[string][]*RuleHandle
+	unitsOrder       []string
+	activationUnits  map[string][]*RuleHandle
+	deactivatedUnits []string
+	expirations      map[string]time.Duration
+	network          *rete
+
+	// unitRuleNames and activationUnitRuleNames are names of rules of units and activation units.
+	unitRuleNames           map[string][]string
+	activationUnitRuleNames map[string][]string
 }
 
 func NewKnowledgeBase(name string) *KnowledgeBase {
@@ -13,10 +53,45 @@ func NewKnowledgeBase(name string) *KnowledgeBase {
 		name:            name,
 		units:           make(map[string][]*RuleHandle),
 		activationUnits: make(map[string][]*RuleHandle),
+		expirations:     make(map[string]time.Duration),
+		resolver:        SalienceResolver(),
+		listeners:       &listeners{},
 	}
 }
 
+// ExpireEvents declares that events of the type of passed value are retracted from sessions once they ended longer
+// than after ago. Without the declaration events are expired only if the type is used by time windows only, then
+// events older than the longest time window are retracted.
+func (k *KnowledgeBase) ExpireEvents(v any, after time.Duration) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.expirations[structKey(v)] = after
+	k.stateless = nil
+
+	return k
+}
+
+// SetConflictResolver sets the resolver that orders rules applicable at the same time for new sessions.
+func (k *KnowledgeBase) SetConflictResolver(resolver ConflictResolver) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.resolver = resolver
+
+	return k
+}
+
 func (k *KnowledgeBase) Add(rule *RuleHandle) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.network = nil
+	k.stateless = nil
+
 	var units []*RuleHandle
 
 	for _, existing := range k.units[rule.unit] {
@@ -51,7 +126,34 @@ func (k *KnowledgeBase) AddUnit(unit string, rules ...*RuleHandle) *KnowledgeBas
 	return k
 }
 
+// NewSession creates a session of rules of the knowledge base. Rules are copied for every session, so changes of the
+// knowledge base don't affect it, unless the knowledge base is built: then sessions share its rules.
 func (k *KnowledgeBase) NewSession() *Session {
+	if k.built {
+		return k.newSession(k.stateless)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.newSession(k.copy())
+}
+
+// newSession creates a session of the rules configured as the knowledge base, the knowledge base must be held.
+func (k *KnowledgeBase) newSession(rules *snapshot) *Session {
+	s := newSession(k.name, rules, k.resolver, k.listeners.clone())
+	s.tracer = k.tracer
+	s.SetMetricsExporter(k.metricsExporter)
+	if k.logger != nil {
+		s.logger = k.logger
+	}
+
+	return s
+}
+
+// copy deep copies rules of the knowledge base, so changes of the knowledge base don't affect sessions created
+// before.
+func (k *KnowledgeBase) copy() *snapshot {
 	units := make(map[string][]*RuleHandle)
 	for k, rr := range k.units {
 		units[k] = copySliceOfRules(rr)
@@ -68,5 +170,43 @@ func (k *KnowledgeBase) NewSession() *Session {
 	deactivatedUnits := make([]string, len(k.deactivatedUnits))
 	copy(deactivatedUnits, k.deactivatedUnits)
 
-	return newSession(k.name, units, unitsOrder, activationUnits, deactivatedUnits)
+	return &snapshot{
+		units:            units,
+		unitsOrder:       unitsOrder,
+		activationUnits:  activationUnits,
+		deactivatedUnits: deactivatedUnits,
+		expirations:      maps.Clone(k.expirations),
+		network:          k.compile(),
+
+		unitRuleNames:           ruleNames(units),
+		activationUnitRuleNames: ruleNames(activationUnits),
+	}
+}
+
+func ruleNames(groups map[string][]*RuleHandle) map[string][]string {
+	names := make(map[string][]string, len(groups))
+	for group, rr := range groups {
+		for _, r := range rr {
+			names[group] = append(names[group], r.name)
+		}
+	}
+
+	return names
+}
+
+// compile builds the network of pattern conditions of all rules once after rules are changed.
+func (k *KnowledgeBase) compile() *rete {
+	if k.network == nil {
+		k.network = newRete()
+
+		for _, unit := range k.unitsOrder {
+			for _, rule := range k.units[unit] {
+				if c, ok := rule.condition.(*PatternCondition); ok {
+					k.network.add(c)
+				}
+			}
+		}
+	}
+
+	return k.network
 }
@@ -1,21 +1,22 @@
 package krools
 
 import (
-	"cmp"
 	"context"
-	"slices"
+	"log/slog"
 )
 
 const UnitMAIN = "MAIN"
 
 type Context interface {
 	Context() context.Context
+	Clock() Clock
+	// Logger returns the logger of the session with names of the rule and the knowledge base attached.
+	Logger() *slog.Logger
+	Halt()
 
-	Set(v any)
-	Get(v any) bool
-	Handle(v any) any
-	HasNot(v any) bool
-	Delete(v any)
+	WorkingMemory
+
+	SetLogical(v any)
 
 	SetLocal(v any)
 	GetLocal(v any) bool
@@ -32,12 +33,6 @@ type ConditionFn func(ctx Context) (bool, error)
 
 func (f ConditionFn) When(ctx Context) (bool, error) { return f(ctx) }
 
-func sortRulesConsiderSalience(rules []*RuleHandle) {
-	slices.SortFunc(rules, func(a, b *RuleHandle) int {
-		return cmp.Compare(a.salience, b.salience) * -1
-	})
-}
-
 func uniq[T comparable](collection []T) []T {
 	result := make([]T, 0, len(collection))
 	seen := make(map[T]struct{}, len(collection))
@@ -2,7 +2,6 @@ package krools_test
 
 import (
 	"context"
-	"log"
 	"testing"
 
 	"github.com/krocos/krools/v2"
@@ -79,11 +78,11 @@ type SomeRule struct{}
 func (s *SomeRule) When(ctx krools.Context) (bool, error) {
 	c := new(Content)
 	if ctx.Get(c) && c.n < 5 {
-		log.Println("need to add n and n now is: ", c.n)
+		ctx.Logger().Debug("need to add n", "n", c.n)
 		return true, nil
 	}
 
-	log.Println("we do not need to add n and n is now: ", c.n)
+	ctx.Logger().Debug("we do not need to add n", "n", c.n)
 
 	return false, nil
 }
@@ -93,7 +92,7 @@ func (s *SomeRule) Then(ctx krools.Context) error {
 	ctx.Get(c)
 	c.n++
 	ctx.Set(c)
-	log.Println("add n and n now is: ", c.n)
+	ctx.Logger().Debug("add n", "n", c.n)
 	return nil
 }
 
@@ -131,3 +130,56 @@ func TestPtr(t *testing.T) {
 		t.Fatal("unexpected counter")
 	}
 }
+
+type Order struct {
+	id    int
+	total int
+}
+
+type LargeOrders struct {
+	n int
+}
+
+func TestFacts(t *testing.T) {
+	k := krools.NewKnowledgeBase("facts base")
+	k.Add(krools.NewInlineRule("count large orders", func(ctx krools.Context) (bool, error) {
+		return ctx.HasNot(LargeOrders{}), nil
+	}, func(ctx krools.Context) error {
+		var n int
+		for h, v := range ctx.Facts(Order{}) {
+			if v.(*Order).total > 100 {
+				n++
+			} else {
+				ctx.Retract(h)
+			}
+		}
+
+		ctx.Set(LargeOrders{n: n})
+
+		return nil
+	}))
+
+	s := k.NewSession()
+
+	s.Insert(Order{id: 1, total: 50})
+	s.Insert(Order{id: 2, total: 150})
+	s.Insert(&Order{id: 3, total: 300})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	l := new(LargeOrders)
+	if !(s.Get(l) && l.n == 2) {
+		t.Fatal("unexpected number of large orders")
+	}
+
+	var left int
+	for range s.Facts(Order{}) {
+		left++
+	}
+
+	if left != 2 {
+		t.Fatalf("unexpected number of orders left: %d", left)
+	}
+}
@@ -0,0 +1,242 @@
+package krools
+
+// ConditionEvent is an evaluation of the condition of a rule. Satisfied and Err are set after the evaluation only.
+type ConditionEvent struct {
+	Rule      *RuleHandle
+	Satisfied bool
+	Err       error
+}
+
+// MatchEvent is an activation of a rule whose condition is satisfied.
+type MatchEvent struct {
+	Activation *Activation
+}
+
+// ActionEvent is an execution of the action of a rule. Err is set after the execution only.
+type ActionEvent struct {
+	Rule *RuleHandle
+	Err  error
+}
+
+// RetractionEvent is a deactivation of rules by an executed rule with Deactivate or ActivationUnit.
+type RetractionEvent struct {
+	Rule  *RuleHandle
+	Rules []string
+}
+
+// UnitEvent is an activation, a deactivation or a focus of units by an executed rule.
+type UnitEvent struct {
+	Rule  *RuleHandle
+	Units []string
+}
+
+// AgendaEventListener observes what happens while rules are firing. Listeners are called holding the session, so
+// they must not use it.
+type AgendaEventListener interface {
+	BeforeConditionEvaluated(e ConditionEvent)
+	AfterConditionEvaluated(e ConditionEvent)
+	// MatchCreated is called when a rule becomes applicable.
+	MatchCreated(e MatchEvent)
+	// MatchCancelled is called when an applicable rule is not applicable anymore before it's executed.
+	MatchCancelled(e MatchEvent)
+	BeforeActionExecuted(e ActionEvent)
+	AfterActionExecuted(e ActionEvent)
+	BeforeRulesRetracted(e RetractionEvent)
+	AfterRulesRetracted(e RetractionEvent)
+	BeforeUnitsActivated(e UnitEvent)
+	AfterUnitsActivated(e UnitEvent)
+	BeforeUnitsDeactivated(e UnitEvent)
+	AfterUnitsDeactivated(e UnitEvent)
+	BeforeUnitsFocused(e UnitEvent)
+	AfterUnitsFocused(e UnitEvent)
+}
+
+// DefaultAgendaEventListener does nothing, embed it to implement only callbacks you need.
+type DefaultAgendaEventListener struct{}
+
+func (DefaultAgendaEventListener) BeforeConditionEvaluated(ConditionEvent) {}
+func (DefaultAgendaEventListener) AfterConditionEvaluated(ConditionEvent)  {}
+func (DefaultAgendaEventListener) MatchCreated(MatchEvent)                 {}
+func (DefaultAgendaEventListener) MatchCancelled(MatchEvent)               {}
+func (DefaultAgendaEventListener) BeforeActionExecuted(ActionEvent)        {}
+func (DefaultAgendaEventListener) AfterActionExecuted(ActionEvent)         {}
+func (DefaultAgendaEventListener) BeforeRulesRetracted(RetractionEvent)    {}
+func (DefaultAgendaEventListener) AfterRulesRetracted(RetractionEvent)     {}
+func (DefaultAgendaEventListener) BeforeUnitsActivated(UnitEvent)          {}
+func (DefaultAgendaEventListener) AfterUnitsActivated(UnitEvent)           {}
+func (DefaultAgendaEventListener) BeforeUnitsDeactivated(UnitEvent)        {}
+func (DefaultAgendaEventListener) AfterUnitsDeactivated(UnitEvent)         {}
+func (DefaultAgendaEventListener) BeforeUnitsFocused(UnitEvent)            {}
+func (DefaultAgendaEventListener) AfterUnitsFocused(UnitEvent)             {}
+
+// WorkingMemoryEvent is a change of working memory. Type is the key of the type of the fact, like
+// "github.com/krocos/krools/v2.Activation". Handle is zero for values set with Set. Fact is the new value for Set,
+// Insert and Update and the removed one for Delete and Retract, Old is the replaced value for Set and Update. Rule is
+// the rule whose action made the change, it's nil for changes made outside of actions.
+type WorkingMemoryEvent struct {
+	Rule   *RuleHandle
+	Type   string
+	Handle FactHandle
+	Fact   any
+	Old    any
+}
+
+// WorkingMemoryEventListener observes changes of working memory of a session. Listeners are called holding the
+// session, so they must not use it.
+type WorkingMemoryEventListener interface {
+	BeforeFactSet(e WorkingMemoryEvent)
+	AfterFactSet(e WorkingMemoryEvent)
+	BeforeFactDeleted(e WorkingMemoryEvent)
+	AfterFactDeleted(e WorkingMemoryEvent)
+	BeforeFactInserted(e WorkingMemoryEvent)
+	AfterFactInserted(e WorkingMemoryEvent)
+	BeforeFactUpdated(e WorkingMemoryEvent)
+	AfterFactUpdated(e WorkingMemoryEvent)
+	BeforeFactRetracted(e WorkingMemoryEvent)
+	AfterFactRetracted(e WorkingMemoryEvent)
+}
+
+// DefaultWorkingMemoryEventListener does nothing, embed it to implement only callbacks you need.
+type DefaultWorkingMemoryEventListener struct{}
+
+func (DefaultWorkingMemoryEventListener) BeforeFactSet(WorkingMemoryEvent)       {}
+func (DefaultWorkingMemoryEventListener) AfterFactSet(WorkingMemoryEvent)        {}
+func (DefaultWorkingMemoryEventListener) BeforeFactDeleted(WorkingMemoryEvent)   {}
+func (DefaultWorkingMemoryEventListener) AfterFactDeleted(WorkingMemoryEvent)    {}
+func (DefaultWorkingMemoryEventListener) BeforeFactInserted(WorkingMemoryEvent)  {}
+func (DefaultWorkingMemoryEventListener) AfterFactInserted(WorkingMemoryEvent)   {}
+func (DefaultWorkingMemoryEventListener) BeforeFactUpdated(WorkingMemoryEvent)   {}
+func (DefaultWorkingMemoryEventListener) AfterFactUpdated(WorkingMemoryEvent)    {}
+func (DefaultWorkingMemoryEventListener) BeforeFactRetracted(WorkingMemoryEvent) {}
+func (DefaultWorkingMemoryEventListener) AfterFactRetracted(WorkingMemoryEvent)  {}
+
+// FactOperation is a kind of change of working memory.
+type FactOperation int
+
+const (
+	FactSet FactOperation = iota
+	FactDeleted
+	FactInserted
+	FactUpdated
+	FactRetracted
+)
+
+func (o FactOperation) String() string {
+	switch o {
+	case FactSet:
+		return "set"
+	case FactDeleted:
+		return "delete"
+	case FactInserted:
+		return "insert"
+	case FactUpdated:
+		return "update"
+	case FactRetracted:
+		return "retract"
+	default:
+		return "unknown"
+	}
+}
+
+// listeners keeps listeners of a session, the rule whose action is executed at the moment and the trace of the fire
+// if tracing is on.
+type listeners struct {
+	agenda []AgendaEventListener
+	memory []WorkingMemoryEventListener
+	rule   *RuleHandle
+	trace  *ExecutionTrace
+}
+
+func (l *listeners) clone() *listeners {
+	return &listeners{
+		agenda: append([]AgendaEventListener(nil), l.agenda...),
+		memory: append([]WorkingMemoryEventListener(nil), l.memory...),
+	}
+}
+
+func (l *listeners) agendaEvent(fn func(listener AgendaEventListener)) {
+	for _, listener := range l.agenda {
+		fn(listener)
+	}
+}
+
+// factChanging notifies listeners before the change and returns the function notifying them after it.
+func (l *listeners) factChanging(op FactOperation, e WorkingMemoryEvent) func() {
+	if l == nil || len(l.memory) == 0 && l.trace == nil {
+		return func() {}
+	}
+
+	e.Rule = l.rule
+
+	for _, listener := range l.memory {
+		switch op {
+		case FactSet:
+			listener.BeforeFactSet(e)
+		case FactDeleted:
+			listener.BeforeFactDeleted(e)
+		case FactInserted:
+			listener.BeforeFactInserted(e)
+		case FactUpdated:
+			listener.BeforeFactUpdated(e)
+		case FactRetracted:
+			listener.BeforeFactRetracted(e)
+		}
+	}
+
+	return func() {
+		l.trace.change(op, e)
+
+		for _, listener := range l.memory {
+			switch op {
+			case FactSet:
+				listener.AfterFactSet(e)
+			case FactDeleted:
+				listener.AfterFactDeleted(e)
+			case FactInserted:
+				listener.AfterFactInserted(e)
+			case FactUpdated:
+				listener.AfterFactUpdated(e)
+			case FactRetracted:
+				listener.AfterFactRetracted(e)
+			}
+		}
+	}
+}
+
+// AddAgendaEventListener adds the listener to the session.
+func (s *Session) AddAgendaEventListener(listener AgendaEventListener) *Session {
+	s.listeners.agenda = append(s.listeners.agenda, listener)
+
+	return s
+}
+
+// AddWorkingMemoryEventListener adds the listener to the session.
+func (s *Session) AddWorkingMemoryEventListener(listener WorkingMemoryEventListener) *Session {
+	s.listeners.memory = append(s.listeners.memory, listener)
+
+	return s
+}
+
+// AddAgendaEventListener adds the listener to sessions created after.
+func (k *KnowledgeBase) AddAgendaEventListener(listener AgendaEventListener) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.listeners.agenda = append(k.listeners.agenda, listener)
+
+	return k
+}
+
+// AddWorkingMemoryEventListener adds the listener to sessions created after.
+func (k *KnowledgeBase) AddWorkingMemoryEventListener(listener WorkingMemoryEventListener) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.listeners.memory = append(k.listeners.memory, listener)
+
+	return k
+}
@@ -0,0 +1,176 @@
+package krools_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type agendaRecorder struct {
+	krools.DefaultAgendaEventListener
+
+	events []string
+}
+
+func (r *agendaRecorder) AfterConditionEvaluated(e krools.ConditionEvent) {
+	r.events = append(r.events, fmt.Sprintf("condition %s %t", e.Rule.Name(), e.Satisfied))
+}
+
+func (r *agendaRecorder) MatchCreated(e krools.MatchEvent) {
+	r.events = append(r.events, "match "+e.Activation.Name)
+}
+
+func (r *agendaRecorder) MatchCancelled(e krools.MatchEvent) {
+	r.events = append(r.events, "cancel "+e.Activation.Name)
+}
+
+func (r *agendaRecorder) BeforeActionExecuted(e krools.ActionEvent) {
+	r.events = append(r.events, "execute "+e.Rule.Name())
+}
+
+func (r *agendaRecorder) AfterRulesRetracted(e krools.RetractionEvent) {
+	r.events = append(r.events, fmt.Sprintf("retract %s %v", e.Rule.Name(), e.Rules))
+}
+
+func (r *agendaRecorder) AfterUnitsFocused(e krools.UnitEvent) {
+	r.events = append(r.events, fmt.Sprintf("focus %s %v", e.Rule.Name(), e.Units))
+}
+
+type memoryRecorder struct {
+	krools.DefaultWorkingMemoryEventListener
+
+	events []string
+}
+
+func rule(e krools.WorkingMemoryEvent) string {
+	if e.Rule == nil {
+		return "-"
+	}
+
+	return e.Rule.Name()
+}
+
+func (r *memoryRecorder) BeforeFactSet(e krools.WorkingMemoryEvent) {
+	r.events = append(r.events, fmt.Sprintf("before set %s %s %v", rule(e), e.Type, e.Old))
+}
+
+func (r *memoryRecorder) AfterFactSet(e krools.WorkingMemoryEvent) {
+	r.events = append(r.events, fmt.Sprintf("set %s %s %v", rule(e), e.Type, e.Fact))
+}
+
+func (r *memoryRecorder) AfterFactDeleted(e krools.WorkingMemoryEvent) {
+	r.events = append(r.events, fmt.Sprintf("delete %s %s", rule(e), e.Type))
+}
+
+func (r *memoryRecorder) AfterFactInserted(e krools.WorkingMemoryEvent) {
+	r.events = append(r.events, fmt.Sprintf("insert %s %s %v", rule(e), e.Type, e.Fact))
+}
+
+func (r *memoryRecorder) AfterFactUpdated(e krools.WorkingMemoryEvent) {
+	r.events = append(r.events, fmt.Sprintf("update %s %v %v", rule(e), e.Old, e.Fact))
+}
+
+func (r *memoryRecorder) AfterFactRetracted(e krools.WorkingMemoryEvent) {
+	r.events = append(r.events, fmt.Sprintf("retract %s %v", rule(e), e.Fact))
+}
+
+func noop(krools.Context) error { return nil }
+
+func TestAgendaEventListener(t *testing.T) {
+	recorder := &agendaRecorder{}
+
+	k := krools.NewKnowledgeBase("listeners base").
+		AddAgendaEventListener(recorder).
+		Add(krools.NewInlineRule("first", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Trigger](ctx), nil
+		}, noop).Salience(1).Deactivate("first", "second").SetFocus("other")).
+		Add(krools.NewInlineRule("second", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Trigger](ctx), nil
+		}, noop)).
+		AddUnit("other", krools.NewInlineRule("third", func(ctx krools.Context) (bool, error) {
+			return false, nil
+		}, noop))
+
+	s := k.NewSession()
+	krools.Set(s, Trigger{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"condition first true",
+		"match first",
+		"condition second true",
+		"match second",
+		"execute first",
+		"retract first [first second]",
+		"focus first [other]",
+		"cancel second",
+		"condition third false",
+		"condition third false",
+	}
+
+	if !slices.Equal(recorder.events, expected) {
+		t.Fatalf("unexpected events:\n%v\nexpected:\n%v", recorder.events, expected)
+	}
+}
+
+func TestWorkingMemoryEventListener(t *testing.T) {
+	recorder := &memoryRecorder{}
+
+	k := krools.NewKnowledgeBase("listeners base").
+		Add(krools.NewInlineRule("count", func(ctx krools.Context) (bool, error) {
+			c, ok := krools.Get[Counter](ctx)
+			return ok && c.n < 2, nil
+		}, func(ctx krools.Context) error {
+			c, _ := krools.Get[Counter](ctx)
+			krools.Set(ctx, Counter{n: c.n + 1})
+			return nil
+		})).
+		Add(krools.NewInlineRule("update", func(ctx krools.Context) (bool, error) {
+			for _, v := range ctx.Facts(Ticket{}) {
+				if v.(*Ticket).n == 1 {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, func(ctx krools.Context) error {
+			for h, v := range ctx.Facts(Ticket{}) {
+				if v.(*Ticket).n == 1 {
+					ctx.Update(h, Ticket{n: 2})
+				}
+			}
+			return nil
+		}))
+
+	s := k.NewSession().AddWorkingMemoryEventListener(recorder)
+	krools.Set(s, Counter{n: 1})
+	h := s.Insert(Ticket{n: 1})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Retract(h)
+	krools.Delete[Counter](s)
+
+	counter := "github.com/krocos/krools/v2_test.Counter"
+	expected := []string{
+		"before set - " + counter + " <nil>",
+		"set - " + counter + " &{1}",
+		"insert - github.com/krocos/krools/v2_test.Ticket &{1}",
+		"before set count " + counter + " &{1}",
+		"set count " + counter + " &{2}",
+		"update update &{1} &{2}",
+		"retract - &{2}",
+		"delete - " + counter,
+	}
+
+	if !slices.Equal(recorder.events, expected) {
+		t.Fatalf("unexpected events:\n%v\nexpected:\n%v", recorder.events, expected)
+	}
+}
@@ -0,0 +1,197 @@
+// Package loader builds rules from documents where conditions and actions are written in the expression language of
+// package expr. A document in JSON looks like:
+//
+//	{
+//	  "rules": [
+//	    {
+//	      "name": "large order discount",
+//	      "salience": 10,
+//	      "no_loop": true,
+//	      "when": "Order.Total >= 100 && !has(Discount)",
+//	      "then": ["set Discount{Percent: 10}"]
+//	    }
+//	  ]
+//	}
+//
+// See the yaml sub-module for YAML documents.
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/expr"
+)
+
+// Document is a list of rules.
+type Document struct {
+	Rules []Definition `json:"rules" yaml:"rules"`
+}
+
+// Definition defines a rule. When is a condition, the rule is always applicable without it. Then are statements of
+// the action. Other fields are attributes of the rule with the same names as methods of krools.RuleHandle.
+type Definition struct {
+	Name            string   `json:"name" yaml:"name"`
+	Unit            string   `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Salience        int      `json:"salience,omitempty" yaml:"salience,omitempty"`
+	NoLoop          bool     `json:"no_loop,omitempty" yaml:"no_loop,omitempty"`
+	ActivationUnit  string   `json:"activation_unit,omitempty" yaml:"activation_unit,omitempty"`
+	Activate        []string `json:"activate,omitempty" yaml:"activate,omitempty"`
+	Deactivate      []string `json:"deactivate,omitempty" yaml:"deactivate,omitempty"`
+	ActivateUnits   []string `json:"activate_units,omitempty" yaml:"activate_units,omitempty"`
+	DeactivateUnits []string `json:"deactivate_units,omitempty" yaml:"deactivate_units,omitempty"`
+	Focus           []string `json:"focus,omitempty" yaml:"focus,omitempty"`
+	When            string   `json:"when,omitempty" yaml:"when,omitempty"`
+	Then            []string `json:"then,omitempty" yaml:"then,omitempty"`
+}
+
+// Load builds rules of the document. Types are types of facts expressions refer to.
+func Load(doc Document, types *expr.Types) ([]*krools.RuleHandle, error) {
+	rules := make([]*krools.RuleHandle, 0, len(doc.Rules))
+	names := make(map[string]struct{}, len(doc.Rules))
+
+	for i, def := range doc.Rules {
+		if def.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+
+		if _, ok := names[def.Name]; ok {
+			return nil, fmt.Errorf("rule '%s' is defined twice", def.Name)
+		}
+
+		names[def.Name] = struct{}{}
+
+		rule, err := Build(def, types)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Build builds the rule of the definition.
+func Build(def Definition, types *expr.Types) (*krools.RuleHandle, error) {
+	when := krools.ConditionFn(func(krools.Context) (bool, error) { return true, nil })
+
+	if def.When != "" {
+		var err error
+
+		when, err = expr.Condition(def.When, types)
+		if err != nil {
+			return nil, fmt.Errorf("compile condition of rule '%s': %w", def.Name, err)
+		}
+	}
+
+	then, err := expr.Action(def.Then, types)
+	if err != nil {
+		return nil, fmt.Errorf("compile action of rule '%s': %w", def.Name, err)
+	}
+
+	rule := krools.NewInlineRule(def.Name, when, then).Salience(def.Salience)
+
+	if def.Unit != "" {
+		rule.Unit(def.Unit)
+	}
+
+	if def.NoLoop {
+		rule.NoLoop()
+	}
+
+	if def.ActivationUnit != "" {
+		rule.ActivationUnit(def.ActivationUnit)
+	}
+
+	if len(def.Activate) > 0 {
+		rule.Activate(def.Activate...)
+	}
+
+	if len(def.Deactivate) > 0 {
+		rule.Deactivate(def.Deactivate...)
+	}
+
+	return rule.
+		ActivateUnits(def.ActivateUnits...).
+		DeactivateUnits(def.DeactivateUnits...).
+		SetFocus(def.Focus...), nil
+}
+
+// LoadJSON decodes the JSON document and builds its rules. Unknown fields are errors.
+func LoadJSON(r io.Reader, types *expr.Types) ([]*krools.RuleHandle, error) {
+	var doc Document
+
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+
+	return Load(doc, types)
+}
+
+// Decoder decodes a document and builds its rules, like LoadJSON.
+type Decoder func(r io.Reader, types *expr.Types) ([]*krools.RuleHandle, error)
+
+// LoadDir builds the knowledge base of rules of JSON documents of the directory, files with the ".json" extension.
+func LoadDir(name, dir string, types *expr.Types) (*krools.KnowledgeBase, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFiles(name, files, LoadJSON, types)
+}
+
+// DirLoader returns LoadDir of the name and the types to be passed to krools.VersionedKnowledgeBase.WatchDir.
+func DirLoader(name string, types *expr.Types) func(dir string) (*krools.KnowledgeBase, error) {
+	return func(dir string) (*krools.KnowledgeBase, error) {
+		return LoadDir(name, dir, types)
+	}
+}
+
+// LoadFiles builds the knowledge base of rules of documents of the files decoded by decode, in order of names of
+// files. Rules with the same name in different files are errors.
+func LoadFiles(name string, files []string, decode Decoder, types *expr.Types) (*krools.KnowledgeBase, error) {
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+
+	kb := krools.NewKnowledgeBase(name)
+	defined := make(map[string]string)
+
+	for _, file := range files {
+		rules, err := loadFile(file, decode, types)
+		if err != nil {
+			return nil, fmt.Errorf("load file '%s': %w", file, err)
+		}
+
+		for _, rule := range rules {
+			if other, ok := defined[rule.Name()]; ok {
+				return nil, fmt.Errorf("rule '%s' of file '%s' is defined in file '%s'", rule.Name(), file, other)
+			}
+
+			defined[rule.Name()] = file
+			kb.Add(rule)
+		}
+	}
+
+	return kb, nil
+}
+
+func loadFile(file string, decode Decoder, types *expr.Types) ([]*krools.RuleHandle, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return decode(f, types)
+}
@@ -0,0 +1,207 @@
+package loader_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/expr"
+	"github.com/krocos/krools/v2/loader"
+)
+
+type Order struct {
+	Total float64
+}
+
+type Discount struct {
+	Percent int
+}
+
+type Audit struct {
+	Rule string
+}
+
+func types() *expr.Types {
+	return expr.NewTypes().
+		Register("Order", Order{}).
+		Register("Discount", Discount{}).
+		Register("Audit", Audit{})
+}
+
+const document = `{
+  "rules": [
+    {
+      "name": "small discount",
+      "when": "Order.Total >= 100",
+      "then": ["set Discount{Percent: 5}"],
+      "deactivate": ["small discount"]
+    },
+    {
+      "name": "large discount",
+      "salience": 10,
+      "when": "Order.Total >= 1000",
+      "then": ["set Discount{Percent: 10}"],
+      "deactivate": ["large discount", "small discount"],
+      "focus": ["audit"]
+    },
+    {
+      "name": "audit",
+      "unit": "audit",
+      "no_loop": true,
+      "then": ["insert Audit{Rule: \"discount\"}"]
+    }
+  ]
+}`
+
+func TestLoadJSON(t *testing.T) {
+	rules, err := loader.LoadJSON(strings.NewReader(document), types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := krools.NewKnowledgeBase("loaded base")
+	for _, rule := range rules {
+		k.Add(rule)
+	}
+
+	for total, percent := range map[float64]int{50: 0, 500: 5, 5000: 10} {
+		s := k.NewSession()
+		krools.Set(s, Order{Total: total})
+
+		if err = s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		d, _ := krools.Get[Discount](s)
+		if d.Percent != percent {
+			t.Errorf("total %v: expected discount %d, got %d", total, percent, d.Percent)
+		}
+
+		var audits int
+		for range s.Facts(Audit{}) {
+			audits++
+		}
+
+		if audits != 1 {
+			t.Errorf("total %v: expected 1 audit, got %d", total, audits)
+		}
+	}
+}
+
+func TestLoadJSON_Errors(t *testing.T) {
+	for doc, msg := range map[string]string{
+		`{"rules": [{"name": "a", "when": "Order.Totl > 1"}]}`:       "compile condition of rule 'a'",
+		`{"rules": [{"name": "a", "then": ["set Discount{X: 1}"]}]}`: "compile action of rule 'a'",
+		`{"rules": [{"name": "a"}, {"name": "a"}]}`:                  "rule 'a' is defined twice",
+		`{"rules": [{"when": "true"}]}`:                              "rule 0 has no name",
+		`{"rules": [{"name": "a", "priority": 1}]}`:                  "unknown field",
+	} {
+		_, err := loader.LoadJSON(strings.NewReader(doc), types())
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected error with %q, got %v", doc, msg, err)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	for file, content := range map[string]string{
+		"a.json":     `{"rules": [{"name": "discount", "when": "Order.Total >= 100", "then": ["set Discount{Percent: 5}"], "deactivate": ["discount"]}]}`,
+		"b.json":     `{"rules": [{"name": "audit", "unit": "audit", "then": ["insert Audit{Rule: \"discount\"}"], "deactivate": ["audit"]}]}`,
+		"readme.txt": "not rules",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	k, err := loader.LoadDir("dir base", dir, types())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := k.NewSession()
+	krools.Set(s, Order{Total: 150})
+
+	if err = s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, _ := krools.Get[Discount](s); d.Percent != 5 {
+		t.Errorf("expected discount 5, got %d", d.Percent)
+	}
+
+	var audits int
+	for range s.Facts(Audit{}) {
+		audits++
+	}
+
+	if audits != 1 {
+		t.Errorf("expected an audit, got %d", audits)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "c.json"), []byte(`{"rules": [{"name": "audit"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = loader.LoadDir("dir base", dir, types()); err == nil || !strings.Contains(err.Error(), "rule 'audit' of file") {
+		t.Errorf("expected duplicate rule error, got %v", err)
+	}
+}
+
+func TestDirLoader(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "discount.json")
+	discount := func(percent int) []byte {
+		return []byte(fmt.Sprintf(`{"rules": [{"name": "discount", "then": ["set Discount{Percent: %d}"], "deactivate": ["discount"]}]}`, percent))
+	}
+
+	if err := os.WriteFile(file, discount(5), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	load := loader.DirLoader("dir base", types())
+
+	k, err := load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := krools.NewVersionedKnowledgeBase(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() { done <- v.WatchDir(ctx, dir, time.Millisecond, load, func(err error) { t.Error(err) }) }()
+
+	if err = os.WriteFile(file, discount(15), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); v.Version() != 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected version 2, got %d", v.Version())
+		}
+	}
+
+	cancel()
+	<-done
+
+	s := v.NewSession()
+	if err = s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, _ := krools.Get[Discount](s); d.Percent != 15 {
+		t.Errorf("expected discount 15, got %d", d.Percent)
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/krocos/krools/v2/loader/yaml
+
+go 1.23
+
+require github.com/krocos/krools/v2 v2.1.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+// Sources of the root module are used for development in this repository only, consumers of the module get the
+// required version of it.
+replace github.com/krocos/krools/v2 => ../../
@@ -0,0 +1,60 @@
+// Package yaml loads rules from YAML documents of the same structure as JSON documents of package loader. Statements
+// with struct literals must be quoted since YAML takes ": " for a mapping:
+//
+//	rules:
+//	  - name: large order discount
+//	    salience: 10
+//	    no_loop: true
+//	    when: Order.Total >= 100 && !has(Discount)
+//	    then:
+//	      - "set Discount{Percent: 10}"
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/expr"
+	"github.com/krocos/krools/v2/loader"
+	"gopkg.in/yaml.v3"
+)
+
+// Load decodes the YAML document and builds its rules. Unknown fields are errors.
+func Load(r io.Reader, types *expr.Types) ([]*krools.RuleHandle, error) {
+	var doc loader.Document
+
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+
+	return loader.Load(doc, types)
+}
+
+// LoadDir builds the knowledge base of rules of YAML documents of the directory, files with the ".yaml" or ".yml"
+// extension.
+func LoadDir(name, dir string, types *expr.Types) (*krools.KnowledgeBase, error) {
+	var files []string
+
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, matches...)
+	}
+
+	return loader.LoadFiles(name, files, Load, types)
+}
+
+// DirLoader returns LoadDir of the name and the types to be passed to krools.VersionedKnowledgeBase.WatchDir.
+func DirLoader(name string, types *expr.Types) func(dir string) (*krools.KnowledgeBase, error) {
+	return func(dir string) (*krools.KnowledgeBase, error) {
+		return LoadDir(name, dir, types)
+	}
+}
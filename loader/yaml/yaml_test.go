@@ -0,0 +1,80 @@
+package yaml_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/expr"
+	"github.com/krocos/krools/v2/loader/yaml"
+)
+
+type Order struct {
+	Total float64
+}
+
+type Discount struct {
+	Percent int
+}
+
+const document = `
+rules:
+  - name: large order discount
+    salience: 10
+    when: Order.Total >= 100 && !has(Discount)
+    then:
+      - "set Discount{Percent: 10}"
+`
+
+func TestLoad(t *testing.T) {
+	types := expr.NewTypes().Register("Order", Order{}).Register("Discount", Discount{})
+
+	rules, err := yaml.Load(strings.NewReader(document), types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := krools.NewKnowledgeBase("yaml base").Add(rules[0]).NewSession()
+	krools.Set(s, Order{Total: 150})
+
+	if err = s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, _ := krools.Get[Discount](s); d.Percent != 10 {
+		t.Errorf("unexpected discount %d", d.Percent)
+	}
+
+	if _, err = yaml.Load(strings.NewReader("rules:\n  - name: a\n    priority: 1\n"), types); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "discounts.yml"), []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	types := expr.NewTypes().Register("Order", Order{}).Register("Discount", Discount{})
+
+	k, err := yaml.LoadDir("yaml dir base", dir, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := k.NewSession()
+	krools.Set(s, Order{Total: 150})
+
+	if err = s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, _ := krools.Get[Discount](s); d.Percent != 10 {
+		t.Errorf("unexpected discount %d", d.Percent)
+	}
+}
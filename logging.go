@@ -0,0 +1,49 @@
+package krools
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardLogger is the logger of sessions without a logger, it discards everything.
+var discardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// SetLogger sets the logger of the session. The session logs cycles, matched rules, retractions and changes of units
+// at debug level, and rules may log to it with Context.Logger. Nothing is logged if it's nil which is the default.
+func (s *Session) SetLogger(logger *slog.Logger) *Session {
+	if logger == nil {
+		logger = discardLogger
+	}
+
+	s.logger = logger
+
+	return s
+}
+
+// SetLogger sets the logger of sessions created after.
+func (k *KnowledgeBase) SetLogger(logger *slog.Logger) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.logger = logger
+
+	return k
+}
+
+// debug logs the message at debug level with the name of the knowledge base attached.
+func (s *Session) debug(ctx *fireContext, msg string, args ...any) {
+	if !s.logger.Enabled(ctx.ctx, slog.LevelDebug) {
+		return
+	}
+
+	s.logger.DebugContext(ctx.ctx, msg, append([]any{slog.String("knowledge_base", s.knowledgeBaseName)}, args...)...)
+}
@@ -0,0 +1,62 @@
+package krools_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	k := krools.NewKnowledgeBase("logged base").
+		SetLogger(logger).
+		Add(krools.NewInlineRule("discount", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Trigger](ctx), nil
+		}, func(ctx krools.Context) error {
+			ctx.Logger().Info("applying discount", "percent", 10)
+			return nil
+		}).Deactivate().SetFocus("other"))
+
+	s := k.NewSession()
+	krools.Set(s, Trigger{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		`level=DEBUG msg="rule matched" knowledge_base="logged base" rule=discount unit=MAIN`,
+		`level=INFO msg="applying discount" knowledge_base="logged base" rule=discount percent=10`,
+		`level=DEBUG msg="rules retracted" knowledge_base="logged base" rule=discount retracted=[discount]`,
+		`level=DEBUG msg="focus set" knowledge_base="logged base" rule=discount units=[other]`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("no line %s in log:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+
+	if err := s.SetLogger(nil).FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() > 0 {
+		t.Errorf("logged without logger:\n%s", buf.String())
+	}
+}
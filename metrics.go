@@ -0,0 +1,255 @@
+package krools
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+)
+
+// RuleKey identifies a rule by its unit and name, rules of different units may have the same name.
+type RuleKey struct {
+	Unit string
+	Name string
+}
+
+// RuleMetrics are counters and timings of a rule.
+type RuleMetrics struct {
+	Rule string
+	Unit string
+
+	// Evaluations is the number of evaluations of the condition and ConditionTime is the time they took.
+	Evaluations   int
+	ConditionTime time.Duration
+	// Matches is the number of times the rule became applicable.
+	Matches int
+	// Executions is the number of executions of the action and ActionTime is the time they took.
+	Executions int
+	ActionTime time.Duration
+	// Errors is the number of errors returned by the condition and the action.
+	Errors int
+}
+
+// Key returns the key of the rule in Metrics.Rules.
+func (m RuleMetrics) Key() RuleKey {
+	return RuleKey{Unit: m.Unit, Name: m.Rule}
+}
+
+// Time is the total time the rule took.
+func (m RuleMetrics) Time() time.Duration {
+	return m.ConditionTime + m.ActionTime
+}
+
+// Metrics are counters and timings of fires of a session.
+type Metrics struct {
+	Fires         int
+	Cycles        int
+	Reevaluations int
+	// PeakReevaluations is the biggest number of reevaluations of a single fire, it's limited by MaxReevaluations.
+	PeakReevaluations int
+	MaxReevaluations  int
+	Evaluations       int
+	Executions        int
+	Duration          time.Duration
+
+	// Rules are metrics of rules by their units and names.
+	Rules map[RuleKey]RuleMetrics
+}
+
+// Slowest returns metrics of at most n rules that took the most time, the slowest first.
+func (m Metrics) Slowest(n int) []RuleMetrics {
+	rules := slices.SortedFunc(maps.Values(m.Rules), func(a, b RuleMetrics) int {
+		if c := cmp.Compare(b.Time(), a.Time()); c != 0 {
+			return c
+		}
+
+		if c := cmp.Compare(a.Unit, b.Unit); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Rule, b.Rule)
+	})
+
+	return rules[:min(n, len(rules))]
+}
+
+func (m *Metrics) add(other Metrics) {
+	m.Fires += other.Fires
+	m.Cycles += other.Cycles
+	m.Reevaluations += other.Reevaluations
+	m.PeakReevaluations = max(m.PeakReevaluations, other.PeakReevaluations)
+	m.MaxReevaluations = other.MaxReevaluations
+	m.Evaluations += other.Evaluations
+	m.Executions += other.Executions
+	m.Duration += other.Duration
+
+	if m.Rules == nil {
+		m.Rules = make(map[RuleKey]RuleMetrics)
+	}
+
+	for key, r := range other.Rules {
+		total := m.Rules[key]
+		total.Rule = r.Rule
+		total.Unit = r.Unit
+		total.Evaluations += r.Evaluations
+		total.ConditionTime += r.ConditionTime
+		total.Matches += r.Matches
+		total.Executions += r.Executions
+		total.ActionTime += r.ActionTime
+		total.Errors += r.Errors
+		m.Rules[key] = total
+	}
+}
+
+// MetricsExporter receives metrics of every fire of a session, so it can add them to metrics of a monitoring system.
+// It's called holding the session, so it must not use it.
+type MetricsExporter interface {
+	ExportMetrics(knowledgeBase string, fire Metrics)
+}
+
+type MetricsExporterFn func(knowledgeBase string, fire Metrics)
+
+func (f MetricsExporterFn) ExportMetrics(knowledgeBase string, fire Metrics) { f(knowledgeBase, fire) }
+
+// fireMetrics collects metrics of a single fire.
+type fireMetrics struct {
+	started time.Time
+	rules   map[*RuleHandle]*RuleMetrics
+}
+
+func (f *fireMetrics) rule(rule *RuleHandle) *RuleMetrics {
+	m, ok := f.rules[rule]
+	if !ok {
+		m = &RuleMetrics{Rule: rule.name, Unit: rule.unit}
+		f.rules[rule] = m
+	}
+
+	return m
+}
+
+// now returns the current time if metrics are collected.
+func (f *fireMetrics) now() time.Time {
+	if f == nil {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+func (f *fireMetrics) evaluated(rule *RuleHandle, started time.Time, err error) {
+	if f == nil {
+		return
+	}
+
+	m := f.rule(rule)
+	m.Evaluations++
+	m.ConditionTime += time.Since(started)
+
+	if err != nil {
+		m.Errors++
+	}
+}
+
+func (f *fireMetrics) matched(rule *RuleHandle) {
+	if f != nil {
+		f.rule(rule).Matches++
+	}
+}
+
+func (f *fireMetrics) fired(rule *RuleHandle) {
+	if f != nil {
+		f.rule(rule).Executions++
+	}
+}
+
+func (f *fireMetrics) executed(rule *RuleHandle, started time.Time, err error) {
+	if f == nil {
+		return
+	}
+
+	m := f.rule(rule)
+	m.ActionTime += time.Since(started)
+
+	if err != nil {
+		m.Errors++
+	}
+}
+
+// SetMetrics turns on or off collecting of metrics, it's off by default. Metrics are accumulated over fires until
+// they're reset.
+func (s *Session) SetMetrics(enabled bool) *Session {
+	s.collectMetrics = enabled
+
+	return s
+}
+
+// SetMetricsExporter sets the exporter of metrics and turns on collecting of metrics.
+func (s *Session) SetMetricsExporter(exporter MetricsExporter) *Session {
+	s.metricsExporter = exporter
+	s.collectMetrics = s.collectMetrics || exporter != nil
+
+	return s
+}
+
+// SetMetricsExporter sets the exporter of metrics of sessions created after, collecting of metrics is turned on for
+// them.
+func (k *KnowledgeBase) SetMetricsExporter(exporter MetricsExporter) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.metricsExporter = exporter
+
+	return k
+}
+
+// Metrics returns metrics accumulated since the session was created or metrics were reset.
+func (s *Session) Metrics() Metrics {
+	var m Metrics
+	s.read(func() {
+		m = s.metrics
+		m.Rules = maps.Clone(s.metrics.Rules)
+	})
+
+	return m
+}
+
+func (s *Session) ResetMetrics() {
+	s.read(func() { s.metrics = Metrics{} })
+}
+
+func (s *Session) startMetrics(ctx *fireContext) {
+	if s.collectMetrics {
+		ctx.metrics = &fireMetrics{started: time.Now(), rules: make(map[*RuleHandle]*RuleMetrics)}
+	}
+}
+
+// finishMetrics adds metrics of the fire to metrics of the session and exports them.
+func (s *Session) finishMetrics(ctx *fireContext) {
+	if ctx.metrics == nil {
+		return
+	}
+
+	fire := Metrics{
+		Fires:             1,
+		Cycles:            ctx.stats.Cycles,
+		Reevaluations:     ctx.stats.Reevaluations,
+		PeakReevaluations: ctx.stats.Reevaluations,
+		MaxReevaluations:  s.maxReevaluations,
+		Evaluations:       ctx.stats.Evaluations,
+		Executions:        ctx.stats.Executions,
+		Duration:          time.Since(ctx.metrics.started),
+		Rules:             make(map[RuleKey]RuleMetrics, len(ctx.metrics.rules)),
+	}
+
+	for _, r := range ctx.metrics.rules {
+		fire.Rules[r.Key()] = *r
+	}
+
+	s.metrics.add(fire)
+
+	if s.metricsExporter != nil {
+		s.metricsExporter.ExportMetrics(s.knowledgeBaseName, fire)
+	}
+}
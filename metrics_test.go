@@ -0,0 +1,121 @@
+package krools_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+func mainRule(name string) krools.RuleKey {
+	return krools.RuleKey{Unit: krools.UnitMAIN, Name: name}
+}
+
+func TestMetrics(t *testing.T) {
+	var exported []krools.Metrics
+
+	k := krools.NewKnowledgeBase("metrics base").
+		SetMetricsExporter(krools.MetricsExporterFn(func(knowledgeBase string, fire krools.Metrics) {
+			if knowledgeBase != "metrics base" {
+				t.Errorf("unexpected knowledge base %s", knowledgeBase)
+			}
+			exported = append(exported, fire)
+		})).
+		Add(krools.NewInlineRule("count", func(ctx krools.Context) (bool, error) {
+			c, _ := krools.Get[Counter](ctx)
+			return c.n < 3, nil
+		}, func(ctx krools.Context) error {
+			c, _ := krools.Get[Counter](ctx)
+			krools.Set(ctx, Counter{n: c.n + 1})
+			return nil
+		})).
+		Add(krools.NewInlineRule("slow", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Trigger](ctx), nil
+		}, func(ctx krools.Context) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		}).Deactivate()).
+		Add(krools.NewInlineRule("failing", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Banner](ctx), nil
+		}, func(ctx krools.Context) error {
+			return errors.New("boom")
+		}))
+
+	s := k.NewSession().SetMaxReevaluations(10)
+	krools.Set(s, Trigger{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.Metrics()
+
+	if m.Fires != 1 || m.Executions != 4 || m.MaxReevaluations != 10 || m.PeakReevaluations != m.Reevaluations {
+		t.Errorf("unexpected metrics: %+v", m)
+	}
+
+	count := m.Rules[mainRule("count")]
+	if count.Evaluations != 4 || count.Matches != 3 || count.Executions != 3 || count.Errors != 0 {
+		t.Errorf("unexpected metrics of count: %+v", count)
+	}
+
+	if slowest := m.Slowest(1); len(slowest) != 1 || slowest[0].Rule != "slow" || slowest[0].ActionTime < 5*time.Millisecond {
+		t.Errorf("unexpected slowest rules: %+v", slowest)
+	}
+
+	krools.Set(s, Banner{})
+
+	if err := s.FireAllRules(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if m = s.Metrics(); m.Fires != 2 || m.Rules[mainRule("failing")].Errors != 1 || m.Rules[mainRule("count")].Executions != 3 {
+		t.Errorf("unexpected metrics: %+v", m)
+	}
+
+	if len(exported) != 2 || exported[1].Fires != 1 || exported[1].Rules[mainRule("failing")].Executions != 1 {
+		t.Errorf("unexpected exported metrics: %+v", exported)
+	}
+
+	s.ResetMetrics()
+
+	if m = s.Metrics(); m.Fires != 0 || len(m.Rules) != 0 {
+		t.Errorf("metrics are not reset: %+v", m)
+	}
+}
+
+func TestMetrics_SameNameInUnits(t *testing.T) {
+	k := krools.NewKnowledgeBase("metrics base").
+		Add(krools.NewInlineRule("check", func(ctx krools.Context) (bool, error) {
+			return !krools.Has[Trigger](ctx), nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Trigger{})
+			return nil
+		})).
+		AddUnit("audit", krools.NewInlineRule("check", func(ctx krools.Context) (bool, error) {
+			c, _ := krools.Get[Counter](ctx)
+			return c.n < 3, nil
+		}, func(ctx krools.Context) error {
+			krools.Handle[Counter](ctx).n++
+			return nil
+		}))
+
+	s := k.NewSession().SetMetrics(true)
+	krools.Set(s, Counter{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := s.Metrics()
+
+	if main := m.Rules[mainRule("check")]; main.Unit != krools.UnitMAIN || main.Executions != 1 {
+		t.Errorf("unexpected metrics of check of unit MAIN: %+v", main)
+	}
+
+	if audit := m.Rules[krools.RuleKey{Unit: "audit", Name: "check"}]; audit.Unit != "audit" || audit.Executions != 3 {
+		t.Errorf("unexpected metrics of check of unit audit: %+v", audit)
+	}
+}
@@ -0,0 +1,65 @@
+// Package otel adapts OpenTelemetry tracing to krools sessions.
+//
+//	s := k.NewSession().SetTracer(otel.NewTracer(otel.GetTracerProvider().Tracer("rules")))
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/krocos/krools/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a krools tracer starting spans with the OpenTelemetry tracer.
+func NewTracer(t trace.Tracer) krools.Tracer {
+	return &tracer{tracer: t}
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attributes ...krools.Attribute) (context.Context, krools.TraceSpan) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attributes)...))
+
+	return ctx, &span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *span) End() {
+	s.span.End()
+}
+
+func convert(attributes []krools.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+
+	for _, a := range attributes {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+
+	return kvs
+}
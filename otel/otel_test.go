@@ -0,0 +1,67 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+	"github.com/krocos/krools/v2/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type Trigger struct{}
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	k := krools.NewKnowledgeBase("traced base").
+		SetTracer(otel.NewTracer(provider.Tracer("test"))).
+		Add(krools.NewInlineRule("fail", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Trigger](ctx), nil
+		}, func(ctx krools.Context) error {
+			return errors.New("boom")
+		}).Salience(5))
+
+	s := k.NewSession()
+	krools.Set(s, Trigger{})
+
+	if err := s.FireAllRules(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+
+	if !slices.Equal(names, []string{"krools.condition", "krools.action", "krools.cycle", "krools.unit", "krools.fire"}) {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+
+	cycle := recorder.Ended()[2].SpanContext().SpanID()
+
+	condition := recorder.Ended()[0]
+	if condition.Status().Code == codes.Error || condition.Parent().SpanID() != cycle {
+		t.Error("condition span is failed or not nested into cycle span")
+	}
+
+	action := recorder.Ended()[1]
+	if action.Status().Code != codes.Error {
+		t.Error("action span is not failed")
+	}
+
+	if !slices.Contains(action.Attributes(), attribute.String(krools.AttributeRule, "fail")) ||
+		!slices.Contains(action.Attributes(), attribute.Int(krools.AttributeSalience, 5)) {
+		t.Errorf("unexpected attributes: %v", action.Attributes())
+	}
+
+	if action.Parent().SpanID() != cycle {
+		t.Error("action span is not nested into cycle span")
+	}
+}
@@ -0,0 +1,406 @@
+package krools
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FactPattern matches inserted facts of some type which satisfy all its constraints and binds them to a variable.
+type FactPattern struct {
+	variable    string
+	typ         reflect.Type
+	key         string
+	constraints []Constraint
+}
+
+// Pattern creates a pattern that matches facts of type T inserted with Insert and the value of type T set with Set.
+// Matched facts are bound to the variable, so constraints of following patterns can refer to their fields with Ref.
+// It panics if T is not a struct or if a constraint refers to a field T doesn't have.
+func Pattern[T any](variable string, constraints ...Constraint) *FactPattern {
+	t := reflect.TypeFor[T]()
+
+	p := &FactPattern{
+		variable:    variable,
+		typ:         t,
+		key:         keyOf[T](),
+		constraints: constraints,
+	}
+
+	for _, c := range constraints {
+		if _, err := fieldIndex(t, c.path); err != nil {
+			panic(err)
+		}
+	}
+
+	return p
+}
+
+// Field selects a field of a fact to constrain. Nested fields are separated by dots, e.g. "Customer.Address.City".
+func Field(path string) FieldSelector {
+	return FieldSelector{path: path}
+}
+
+// Ref refers to a field of a fact bound to the variable by one of previous patterns. Pass it to a constraint to join
+// facts of different patterns.
+func Ref(variable, path string) FieldRef {
+	return FieldRef{variable: variable, path: path}
+}
+
+type FieldSelector struct {
+	path string
+}
+
+type FieldRef struct {
+	variable string
+	path     string
+}
+
+type Operator string
+
+const (
+	OpEq    Operator = "=="
+	OpNotEq Operator = "!="
+	OpGt    Operator = ">"
+	OpGe    Operator = ">="
+	OpLt    Operator = "<"
+	OpLe    Operator = "<="
+)
+
+// Constraint is a test of a field of a fact against a constant value or against a field of a fact bound by one of
+// previous patterns.
+type Constraint struct {
+	path  string
+	op    Operator
+	value any
+	ref   *FieldRef
+}
+
+func (f FieldSelector) Eq(v any) Constraint    { return f.constraint(OpEq, v) }
+func (f FieldSelector) NotEq(v any) Constraint { return f.constraint(OpNotEq, v) }
+func (f FieldSelector) Gt(v any) Constraint    { return f.constraint(OpGt, v) }
+func (f FieldSelector) Ge(v any) Constraint    { return f.constraint(OpGe, v) }
+func (f FieldSelector) Lt(v any) Constraint    { return f.constraint(OpLt, v) }
+func (f FieldSelector) Le(v any) Constraint    { return f.constraint(OpLe, v) }
+
+func (f FieldSelector) constraint(op Operator, v any) Constraint {
+	c := Constraint{path: f.path, op: op, value: v}
+
+	if ref, ok := v.(FieldRef); ok {
+		c.value = nil
+		c.ref = &ref
+	}
+
+	return c
+}
+
+func (c Constraint) String() string {
+	if c.ref != nil {
+		return fmt.Sprintf("%s %s %s.%s", c.path, c.op, c.ref.variable, c.ref.path)
+	}
+
+	return fmt.Sprintf("%s %s %#v", c.path, c.op, c.value)
+}
+
+// PatternCondition is a declarative condition that is satisfied when there is at least one combination of facts
+// matching all its patterns. Knowledge base compiles pattern conditions of its rules into a network shared by all
+// rules, so common patterns are tested once and matches are maintained incrementally when facts are inserted,
+// updated and retracted and when values are set and deleted.
+//
+// Matches found by the condition are available to the action as PatternMatches local.
+type PatternCondition struct {
+	patterns  []*FactPattern
+	variables []string
+}
+
+// When creates a condition from patterns. It panics if there are no patterns, if variables are not unique or if a
+// constraint refers to a variable that is not bound by one of previous patterns.
+func When(patterns ...*FactPattern) *PatternCondition {
+	if len(patterns) == 0 {
+		panic("at least one pattern is required")
+	}
+
+	c := &PatternCondition{patterns: patterns}
+
+	for i, p := range patterns {
+		if contains(c.variables, p.variable) {
+			panic(fmt.Sprintf("variable '%s' is bound twice", p.variable))
+		}
+
+		for _, constraint := range p.constraints {
+			if constraint.ref == nil {
+				continue
+			}
+
+			j := c.position(constraint.ref.variable)
+			if j < 0 {
+				panic(fmt.Sprintf("variable '%s' of pattern %d is not bound by previous patterns", constraint.ref.variable, i))
+			}
+
+			if _, err := fieldIndex(patterns[j].typ, constraint.ref.path); err != nil {
+				panic(err)
+			}
+		}
+
+		c.variables = append(c.variables, p.variable)
+	}
+
+	return c
+}
+
+func (c *PatternCondition) position(variable string) int {
+	for i, v := range c.variables {
+		if v == variable {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// specificity is the number of patterns and constraints of the condition.
+func (c *PatternCondition) specificity() int {
+	n := len(c.patterns)
+
+	for _, p := range c.patterns {
+		n += len(p.constraints)
+	}
+
+	return n
+}
+
+func (c *PatternCondition) When(ctx Context) (bool, error) {
+	fc, ok := ctx.(*fireContext)
+	if !ok || fc.rete == nil {
+		return false, errors.New("pattern condition can be evaluated only by a session")
+	}
+
+	for _, p := range c.patterns {
+		fc.readFacts(p.key)
+	}
+
+	tuples := fc.rete.matches(c)
+	if len(tuples) == 0 {
+		return false, nil
+	}
+
+	matches := make([]Match, 0, len(tuples))
+	for _, t := range tuples {
+		m := Match{variables: c.variables, handles: t, facts: make([]any, len(t))}
+		for i, h := range t {
+			m.facts[i] = fc.rete.fact(h)
+		}
+
+		matches = append(matches, m)
+	}
+
+	fc.local().Set(PatternMatches{Matches: matches})
+
+	return true, nil
+}
+
+// NewPatternRule creates a rule with a declarative condition.
+func NewPatternRule(name string, condition *PatternCondition, action ActionFn) *RuleHandle {
+	return newRule(name, condition, action)
+}
+
+// PatternMatches is set as a local of a rule with pattern condition before its action is executed.
+type PatternMatches struct {
+	Matches []Match
+}
+
+// Match is a combination of facts that satisfies all patterns of a condition.
+type Match struct {
+	variables []string
+	handles   []FactHandle
+	facts     []any
+}
+
+// Handle returns the handle of the fact bound to the variable. A value set with Set has a handle only within matches,
+// it can't be updated or retracted by it.
+func (m Match) Handle(variable string) FactHandle {
+	for i, v := range m.variables {
+		if v == variable {
+			return m.handles[i]
+		}
+	}
+
+	return 0
+}
+
+// Fact returns a pointer to the fact bound to the variable.
+func (m Match) Fact(variable string) any {
+	for i, v := range m.variables {
+		if v == variable {
+			return m.facts[i]
+		}
+	}
+
+	return nil
+}
+
+// Bound returns a pointer to the fact of type T bound to the variable or nil if there is no such fact.
+func Bound[T any](m Match, variable string) *T {
+	v, _ := m.Fact(variable).(*T)
+
+	return v
+}
+
+// fieldIndex resolves the dotted path of a field to indexes of struct fields.
+func fieldIndex(t reflect.Type, path string) ([][]int, error) {
+	var index [][]int
+
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field '%s' of path '%s' is not in a struct", name, path)
+		}
+
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("type %s has no field '%s' of path '%s'", t, name, path)
+		}
+
+		index = append(index, f.Index)
+		t = f.Type
+	}
+
+	return index, nil
+}
+
+// fieldValue returns the value of the field of passed pointer to a struct, it returns false if there is a nil pointer
+// on the path.
+func fieldValue(v any, index [][]int) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+
+	for _, i := range index {
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			rv = rv.Elem()
+		}
+
+		rv = rv.FieldByIndex(i)
+	}
+
+	return rv, true
+}
+
+func testValues(op Operator, a, b reflect.Value) bool {
+	for a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface {
+		if a.IsNil() {
+			return false
+		}
+
+		a = a.Elem()
+	}
+
+	for b.Kind() == reflect.Ptr || b.Kind() == reflect.Interface {
+		if b.IsNil() {
+			return false
+		}
+
+		b = b.Elem()
+	}
+
+	if r, ok := compareValues(a, b); ok {
+		switch op {
+		case OpEq:
+			return r == 0
+		case OpNotEq:
+			return r != 0
+		case OpGt:
+			return r > 0
+		case OpGe:
+			return r >= 0
+		case OpLt:
+			return r < 0
+		case OpLe:
+			return r <= 0
+		}
+	}
+
+	switch op {
+	case OpEq:
+		return equalValues(a, b)
+	case OpNotEq:
+		return !equalValues(a, b)
+	}
+
+	return false
+}
+
+func compareValues(a, b reflect.Value) (int, bool) {
+	switch {
+	case isInt(a) && isInt(b):
+		return cmp.Compare(a.Int(), b.Int()), true
+	case isUint(a) && isUint(b):
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case isNumber(a) && isNumber(b):
+		return cmp.Compare(toFloat(a), toFloat(b)), true
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return cmp.Compare(a.String(), b.String()), true
+	}
+
+	if a.Type() == b.Type() && a.CanInterface() && b.CanInterface() {
+		if m := a.MethodByName("Compare"); m.IsValid() &&
+			m.Type().NumIn() == 1 && m.Type().In(0) == b.Type() &&
+			m.Type().NumOut() == 1 && m.Type().Out(0).Kind() == reflect.Int {
+			return int(m.Call([]reflect.Value{b})[0].Int()), true
+		}
+	}
+
+	return 0, false
+}
+
+func equalValues(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Bool && b.Kind() == reflect.Bool {
+		return a.Bool() == b.Bool()
+	}
+
+	if a.Type() != b.Type() || !a.Comparable() {
+		return false
+	}
+
+	return a.Equal(b)
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(v reflect.Value) bool {
+	return isInt(v) || isUint(v) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v):
+		return float64(v.Int())
+	case isUint(v):
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
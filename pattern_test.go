@@ -0,0 +1,113 @@
+package krools_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Customer struct {
+	ID   int
+	Tier string
+}
+
+type Purchase struct {
+	CustomerID int
+	Amount     int
+	Discounted bool
+}
+
+func TestPatternRule(t *testing.T) {
+	k := krools.NewKnowledgeBase("patterns base").
+		Add(krools.NewPatternRule("discount for gold customers",
+			krools.When(
+				krools.Pattern[Customer]("c", krools.Field("Tier").Eq("gold")),
+				krools.Pattern[Purchase]("p",
+					krools.Field("CustomerID").Eq(krools.Ref("c", "ID")),
+					krools.Field("Amount").Ge(100),
+					krools.Field("Discounted").Eq(false),
+				),
+			),
+			func(ctx krools.Context) error {
+				m := new(krools.PatternMatches)
+				ctx.GetLocal(m)
+
+				for _, match := range m.Matches {
+					p := krools.Bound[Purchase](match, "p")
+					p.Amount -= p.Amount / 10
+					p.Discounted = true
+					ctx.Update(match.Handle("p"), p)
+				}
+
+				return nil
+			},
+		))
+
+	s := k.NewSession()
+
+	s.Insert(Customer{ID: 1, Tier: "gold"})
+	s.Insert(Customer{ID: 2, Tier: "silver"})
+	gold := s.Insert(Purchase{CustomerID: 1, Amount: 200})
+	small := s.Insert(Purchase{CustomerID: 1, Amount: 50})
+	silver := s.Insert(Purchase{CustomerID: 2, Amount: 200})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for h, want := range map[krools.FactHandle]int{gold: 180, small: 50, silver: 200} {
+		if p := s.Fact(h).(*Purchase); p.Amount != want {
+			t.Fatalf("unexpected amount of purchase %d: %d", h, p.Amount)
+		}
+	}
+
+	late := s.Insert(Purchase{CustomerID: 1, Amount: 1000})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := s.Fact(late).(*Purchase); p.Amount != 900 {
+		t.Fatalf("unexpected amount of late purchase: %d", p.Amount)
+	}
+}
+
+func TestPatternRule_SetValues(t *testing.T) {
+	var fired int
+
+	k := krools.NewKnowledgeBase("patterns base").
+		Add(krools.NewPatternRule("gold purchase",
+			krools.When(
+				krools.Pattern[Customer]("c", krools.Field("Tier").Eq("gold")),
+				krools.Pattern[Purchase]("p", krools.Field("CustomerID").Eq(krools.Ref("c", "ID"))),
+			),
+			func(ctx krools.Context) error {
+				fired++
+				return nil
+			},
+		).Deactivate())
+
+	s := k.NewSession()
+	s.Insert(Purchase{CustomerID: 1, Amount: 100})
+
+	for _, step := range []struct {
+		write func()
+		fired int
+	}{
+		{func() { krools.Set(s, Customer{ID: 1, Tier: "gold"}) }, 1},
+		{func() { krools.Set(s, Customer{ID: 1, Tier: "silver"}) }, 1},
+		{func() { krools.Set(s, Customer{ID: 1, Tier: "gold"}) }, 2},
+		{func() { krools.Delete[Customer](s) }, 2},
+	} {
+		step.write()
+
+		if err := s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if fired != step.fired {
+			t.Fatalf("expected rule to be fired %d times, got %d", step.fired, fired)
+		}
+	}
+}
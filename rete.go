@@ -0,0 +1,487 @@
+package krools
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rete is a network compiled from pattern conditions of a knowledge base. Alpha nodes test single facts against
+// constant constraints, beta nodes join facts passed alpha nodes with partial matches of previous patterns. Nodes
+// with the same tests are shared by all patterns and conditions. The network itself is immutable, its state lives in
+// reteMemory of every session.
+type rete struct {
+	alphas      []*alphaNode
+	betas       []*betaNode
+	alphasByKey map[string][]*alphaNode
+	terminals   map[*PatternCondition]*betaNode
+}
+
+type alphaNode struct {
+	id         int
+	key        string
+	signature  string
+	tests      []alphaTest
+	successors []*betaNode
+}
+
+type alphaTest struct {
+	index [][]int
+	op    Operator
+	value reflect.Value
+}
+
+type betaNode struct {
+	id        int
+	signature string
+	parent    *betaNode
+	alpha     *alphaNode
+	tests     []joinTest
+	children  []*betaNode
+}
+
+type joinTest struct {
+	index    [][]int
+	op       Operator
+	position int
+	refIndex [][]int
+}
+
+func newRete() *rete {
+	return &rete{
+		alphasByKey: make(map[string][]*alphaNode),
+		terminals:   make(map[*PatternCondition]*betaNode),
+	}
+}
+
+func (n *rete) add(c *PatternCondition) {
+	if _, ok := n.terminals[c]; ok {
+		return
+	}
+
+	var parent *betaNode
+
+	for _, p := range c.patterns {
+		var (
+			literals []Constraint
+			joins    []Constraint
+		)
+
+		for _, constraint := range p.constraints {
+			if constraint.ref != nil {
+				joins = append(joins, constraint)
+			} else {
+				literals = append(literals, constraint)
+			}
+		}
+
+		parent = n.beta(parent, n.alpha(p, literals), c, p, joins)
+	}
+
+	n.terminals[c] = parent
+}
+
+func (n *rete) alpha(p *FactPattern, constraints []Constraint) *alphaNode {
+	signatures := make([]string, 0, len(constraints))
+	for _, c := range constraints {
+		signatures = append(signatures, c.String())
+	}
+
+	slices.Sort(signatures)
+	signature := p.key + "(" + strings.Join(signatures, ", ") + ")"
+
+	for _, a := range n.alphasByKey[p.key] {
+		if a.signature == signature {
+			return a
+		}
+	}
+
+	a := &alphaNode{id: len(n.alphas), key: p.key, signature: signature}
+
+	for _, c := range constraints {
+		index, _ := fieldIndex(p.typ, c.path)
+		a.tests = append(a.tests, alphaTest{index: index, op: c.op, value: reflect.ValueOf(c.value)})
+	}
+
+	n.alphas = append(n.alphas, a)
+	n.alphasByKey[p.key] = append(n.alphasByKey[p.key], a)
+
+	return a
+}
+
+func (n *rete) beta(parent *betaNode, alpha *alphaNode, c *PatternCondition, p *FactPattern, constraints []Constraint) *betaNode {
+	tests := make([]joinTest, 0, len(constraints))
+	signatures := make([]string, 0, len(constraints))
+
+	for _, constraint := range constraints {
+		position := c.position(constraint.ref.variable)
+		index, _ := fieldIndex(p.typ, constraint.path)
+		refIndex, _ := fieldIndex(c.patterns[position].typ, constraint.ref.path)
+
+		tests = append(tests, joinTest{index: index, op: constraint.op, position: position, refIndex: refIndex})
+		signatures = append(signatures, fmt.Sprintf("%s %s $%d.%s", constraint.path, constraint.op, position, constraint.ref.path))
+	}
+
+	slices.Sort(signatures)
+	signature := alpha.signature + "[" + strings.Join(signatures, ", ") + "]"
+
+	siblings := alpha.successors
+	if parent != nil {
+		signature = parent.signature + " & " + signature
+		siblings = parent.children
+	}
+
+	for _, b := range siblings {
+		if b.signature == signature {
+			return b
+		}
+	}
+
+	b := &betaNode{id: len(n.betas), signature: signature, parent: parent, alpha: alpha, tests: tests}
+
+	n.betas = append(n.betas, b)
+	alpha.successors = append(alpha.successors, b)
+
+	if parent != nil {
+		parent.children = append(parent.children, b)
+	}
+
+	return b
+}
+
+func (a *alphaNode) accepts(v any) bool {
+	for _, t := range a.tests {
+		fv, ok := fieldValue(v, t.index)
+		if !ok || !testValues(t.op, fv, t.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reteMemory keeps facts passed alpha nodes and partial matches of beta nodes of a network for a session. Changes of
+// facts are collected as they happen and applied to the network when matches are requested, so facts changed by an
+// action through pointers are tested after the action is done.
+//
+// Values written with Set take part in the network as facts too. They have no handles in working memory, so the
+// memory gives every value its own handle and tests it again whenever the version of its type changes.
+type reteMemory struct {
+	net   *rete
+	c     *structTypeContainer
+	alpha []*handleSet
+	beta  []*tupleSet
+
+	dirty      map[FactHandle]string
+	dirtyTypes map[string]struct{}
+
+	values      map[string]reteValue
+	valueHandle map[FactHandle]string
+}
+
+// reteValue is the handle given to the value of a type key and the version of the key it was tested at.
+type reteValue struct {
+	h       FactHandle
+	version uint64
+}
+
+func newReteMemory(net *rete, c *structTypeContainer) *reteMemory {
+	m := &reteMemory{
+		net:        net,
+		c:          c,
+		alpha:      make([]*handleSet, len(net.alphas)),
+		beta:       make([]*tupleSet, len(net.betas)),
+		dirty:      make(map[FactHandle]string),
+		dirtyTypes: make(map[string]struct{}),
+
+		values:      make(map[string]reteValue),
+		valueHandle: make(map[FactHandle]string),
+	}
+
+	for i := range m.alpha {
+		m.alpha[i] = newHandleSet()
+	}
+
+	for i := range m.beta {
+		m.beta[i] = newTupleSet()
+	}
+
+	for key := range net.alphasByKey {
+		m.dirtyTypes[key] = struct{}{}
+	}
+
+	c.observe(m.changed)
+
+	return m
+}
+
+// changed marks the fact or all facts of the type if the handle is zero to be tested again.
+func (m *reteMemory) changed(key string, h FactHandle) {
+	if _, ok := m.net.alphasByKey[key]; !ok {
+		return
+	}
+
+	if h == 0 {
+		m.dirtyTypes[key] = struct{}{}
+	} else {
+		m.dirty[h] = key
+	}
+}
+
+// fact returns the fact behind the handle, either inserted or set.
+func (m *reteMemory) fact(h FactHandle) any {
+	if key, ok := m.valueHandle[h]; ok {
+		return m.c.vals[key]
+	}
+
+	return m.c.Fact(h)
+}
+
+// flushValues tests again values set or deleted since they were tested last time.
+func (m *reteMemory) flushValues() {
+	for key := range m.net.alphasByKey {
+		version := m.c.versions[key]
+
+		value, ok := m.values[key]
+		if ok && value.version == version {
+			continue
+		}
+
+		if ok {
+			m.retract(key, value.h)
+		}
+
+		v, exists := m.c.vals[key]
+		if !exists {
+			if ok {
+				delete(m.values, key)
+				delete(m.valueHandle, value.h)
+			}
+
+			continue
+		}
+
+		if !ok {
+			value.h = nextFactHandle()
+			m.valueHandle[value.h] = key
+		}
+
+		value.version = version
+		m.values[key] = value
+
+		m.insert(key, value.h, v)
+	}
+}
+
+func (m *reteMemory) flush() {
+	m.flushValues()
+
+	for key := range m.dirtyTypes {
+		for h := range m.c.factsKey(key) {
+			m.dirty[h] = key
+		}
+
+		for _, a := range m.net.alphasByKey[key] {
+			for _, h := range m.alpha[a.id].handles {
+				m.dirty[h] = key
+			}
+		}
+	}
+
+	clear(m.dirtyTypes)
+
+	handles := make([]FactHandle, 0, len(m.dirty))
+	for h := range m.dirty {
+		handles = append(handles, h)
+	}
+
+	slices.Sort(handles)
+
+	for _, h := range handles {
+		key := m.dirty[h]
+
+		m.retract(key, h)
+
+		if v := m.fact(h); v != nil {
+			m.insert(key, h, v)
+		}
+	}
+
+	clear(m.dirty)
+}
+
+func (m *reteMemory) matches(c *PatternCondition) [][]FactHandle {
+	m.flush()
+
+	terminal, ok := m.net.terminals[c]
+	if !ok {
+		return nil
+	}
+
+	return slices.Clone(m.beta[terminal.id].tuples)
+}
+
+func (m *reteMemory) insert(key string, h FactHandle, v any) {
+	for _, a := range m.net.alphasByKey[key] {
+		if !a.accepts(v) {
+			continue
+		}
+
+		m.alpha[a.id].add(h)
+
+		for _, b := range a.successors {
+			m.rightActivate(b, h)
+		}
+	}
+}
+
+func (m *reteMemory) retract(key string, h FactHandle) {
+	for _, a := range m.net.alphasByKey[key] {
+		if !m.alpha[a.id].remove(h) {
+			continue
+		}
+
+		for _, b := range a.successors {
+			m.removeTuples(b, h)
+		}
+	}
+}
+
+func (m *reteMemory) rightActivate(b *betaNode, h FactHandle) {
+	if b.parent == nil {
+		m.addTuple(b, []FactHandle{h})
+		return
+	}
+
+	for _, t := range slices.Clone(m.beta[b.parent.id].tuples) {
+		if m.joins(b, t, h) {
+			m.addTuple(b, append(slices.Clone(t), h))
+		}
+	}
+}
+
+func (m *reteMemory) addTuple(b *betaNode, t []FactHandle) {
+	if !m.beta[b.id].add(t) {
+		return
+	}
+
+	for _, child := range b.children {
+		for _, h := range slices.Clone(m.alpha[child.alpha.id].handles) {
+			if m.joins(child, t, h) {
+				m.addTuple(child, append(slices.Clone(t), h))
+			}
+		}
+	}
+}
+
+func (m *reteMemory) removeTuples(b *betaNode, h FactHandle) {
+	if !m.beta[b.id].remove(h) {
+		return
+	}
+
+	for _, child := range b.children {
+		m.removeTuples(child, h)
+	}
+}
+
+func (m *reteMemory) joins(b *betaNode, t []FactHandle, h FactHandle) bool {
+	v := m.fact(h)
+
+	for _, test := range b.tests {
+		a, ok := fieldValue(v, test.index)
+		if !ok {
+			return false
+		}
+
+		ref, ok := fieldValue(m.fact(t[test.position]), test.refIndex)
+		if !ok || !testValues(test.op, a, ref) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type handleSet struct {
+	handles []FactHandle
+	index   map[FactHandle]struct{}
+}
+
+func newHandleSet() *handleSet {
+	return &handleSet{index: make(map[FactHandle]struct{})}
+}
+
+func (s *handleSet) add(h FactHandle) {
+	if _, ok := s.index[h]; ok {
+		return
+	}
+
+	s.index[h] = struct{}{}
+	s.handles = append(s.handles, h)
+}
+
+func (s *handleSet) remove(h FactHandle) bool {
+	if _, ok := s.index[h]; !ok {
+		return false
+	}
+
+	delete(s.index, h)
+	s.handles = slices.DeleteFunc(s.handles, func(e FactHandle) bool { return e == h })
+
+	return true
+}
+
+type tupleSet struct {
+	tuples [][]FactHandle
+	index  map[string]struct{}
+}
+
+func newTupleSet() *tupleSet {
+	return &tupleSet{index: make(map[string]struct{})}
+}
+
+func (s *tupleSet) add(t []FactHandle) bool {
+	k := tupleKey(t)
+	if _, ok := s.index[k]; ok {
+		return false
+	}
+
+	s.index[k] = struct{}{}
+	s.tuples = append(s.tuples, t)
+
+	return true
+}
+
+// remove removes all tuples containing the handle and reports if any was removed.
+func (s *tupleSet) remove(h FactHandle) bool {
+	var removed bool
+
+	s.tuples = slices.DeleteFunc(s.tuples, func(t []FactHandle) bool {
+		if !slices.Contains(t, h) {
+			return false
+		}
+
+		delete(s.index, tupleKey(t))
+		removed = true
+
+		return true
+	})
+
+	return removed
+}
+
+func tupleKey(t []FactHandle) string {
+	var b strings.Builder
+
+	for i, h := range t {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(strconv.FormatUint(uint64(h), 10))
+	}
+
+	return b.String()
+}
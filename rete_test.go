@@ -0,0 +1,93 @@
+package krools
+
+import (
+	"testing"
+)
+
+type reteOrder struct {
+	ID         int
+	CustomerID int
+	Status     string
+	Total      float64
+}
+
+type reteCustomer struct {
+	ID   int
+	Tier string
+}
+
+func TestRete_SharesNodes(t *testing.T) {
+	newOrders := Pattern[reteOrder]("o", Field("Status").Eq("new"))
+
+	n := newRete()
+	n.add(When(newOrders, Pattern[reteCustomer]("c", Field("ID").Eq(Ref("o", "CustomerID")))))
+	n.add(When(
+		Pattern[reteOrder]("order", Field("Status").Eq("new")),
+		Pattern[reteCustomer]("customer", Field("ID").Eq(Ref("order", "CustomerID")), Field("Tier").Eq("gold")),
+	))
+	n.add(When(Pattern[reteOrder]("o", Field("Status").Eq("new"), Field("Total").Gt(100))))
+
+	if len(n.alphas) != 4 {
+		t.Fatalf("unexpected number of alpha nodes: %d", len(n.alphas))
+	}
+
+	if len(n.betas) != 4 {
+		t.Fatalf("unexpected number of beta nodes: %d", len(n.betas))
+	}
+}
+
+func TestReteMemory_Incremental(t *testing.T) {
+	cond := When(
+		Pattern[reteOrder]("o", Field("Status").Eq("new")),
+		Pattern[reteCustomer]("c", Field("ID").Eq(Ref("o", "CustomerID"))),
+	)
+
+	n := newRete()
+	n.add(cond)
+
+	c := newStructTypeContainer()
+	m := newReteMemory(n, c)
+
+	h := c.Insert(reteOrder{ID: 1, CustomerID: 10, Status: "new"})
+	c.Insert(reteOrder{ID: 2, CustomerID: 20, Status: "new"})
+	c.Insert(reteCustomer{ID: 10})
+
+	if matches := m.matches(cond); len(matches) != 1 {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+
+	c.Insert(reteCustomer{ID: 20})
+
+	if matches := m.matches(cond); len(matches) != 2 {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+
+	c.Update(h, reteOrder{ID: 1, CustomerID: 10, Status: "paid"})
+
+	if matches := m.matches(cond); len(matches) != 1 || matches[0][0] == h {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+
+	c.Fact(h).(*reteOrder).Status = "new"
+	c.changed(structKey(reteOrder{}), 0)
+
+	if matches := m.matches(cond); len(matches) != 2 {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+
+	c.Retract(h)
+
+	if matches := m.matches(cond); len(matches) != 1 {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+}
+
+func TestPattern_UnknownField(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("must panic")
+		}
+	}()
+
+	Pattern[reteOrder]("o", Field("Unknown").Eq(1))
+}
@@ -1,16 +1,24 @@
 package krools
 
+// retraction is the cause of a retraction of a rule.
+type retraction struct {
+	// by is the name of the rule which retracted the rule, it's empty if the unit was deactivated before firing.
+	by string
+	// unit is the deactivated unit of the rule, it's empty if the rule was retracted by its name.
+	unit string
+}
+
 type retracting struct {
-	retracted map[string]struct{}
+	retracted map[string]retraction
 }
 
 func newRetracting() *retracting {
-	return &retracting{retracted: make(map[string]struct{})}
+	return &retracting{retracted: make(map[string]retraction)}
 }
 
-func (r *retracting) add(rules ...string) {
+func (r *retracting) add(cause retraction, rules ...string) {
 	for _, rule := range rules {
-		r.retracted[rule] = struct{}{}
+		r.retracted[rule] = cause
 	}
 }
 
@@ -25,3 +33,7 @@ func (r *retracting) isRetracted(rule string) bool {
 
 	return ok
 }
+
+func (r *retracting) cause(rule string) retraction {
+	return r.retracted[rule]
+}
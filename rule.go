@@ -1,5 +1,7 @@
 package krools
 
+import "sync/atomic"
+
 type Rule interface {
 	Condition
 	Action
@@ -13,6 +15,10 @@ type Action interface {
 	Then(ctx Context) error
 }
 
+// ruleRevision counts changes of rules made by methods of RuleHandle, knowledge bases drop snapshots of their rules
+// made before a change.
+var ruleRevision atomic.Uint64
+
 type RuleHandle struct {
 	name           string
 	salience       int
@@ -23,12 +29,11 @@ type RuleHandle struct {
 	unit           string
 	activationUnit *string
 	noLoop         bool
+	timer          *ruleTimer
 
 	deactivateUnits []string
 	activateUnits   []string
 	focusUnits      []string
-
-	locals *structTypeContainer
 }
 
 func NewRule(name string, rule Rule) *RuleHandle {
@@ -45,8 +50,20 @@ func newRule(name string, condition Condition, action Action) *RuleHandle {
 	}
 }
 
+// NewInlineRule creates a rule of functions. A rule without a condition is always applicable and a rule without an
+// action only changes the flow with its attributes.
 func NewInlineRule(name string, condition ConditionFn, action ActionFn) *RuleHandle {
-	return newRule(name, condition, action)
+	r := newRule(name, nil, nil)
+
+	if condition != nil {
+		r.condition = condition
+	}
+
+	if action != nil {
+		r.action = action
+	}
+
+	return r
 }
 
 func copyRule(rule *RuleHandle) *RuleHandle {
@@ -60,6 +77,7 @@ func copyRule(rule *RuleHandle) *RuleHandle {
 		unit:            rule.unit,
 		activationUnit:  rule.activationUnit,
 		noLoop:          rule.noLoop,
+		timer:           rule.timer,
 		deactivateUnits: make([]string, len(rule.deactivateUnits)),
 		activateUnits:   make([]string, len(rule.activateUnits)),
 		focusUnits:      make([]string, len(rule.focusUnits)),
@@ -72,8 +90,6 @@ func copyRule(rule *RuleHandle) *RuleHandle {
 	copy(nr.activateUnits, rule.activateUnits)
 	copy(nr.focusUnits, rule.focusUnits)
 
-	nr.locals = newStructTypeContainer()
-
 	return nr
 }
 
@@ -86,8 +102,14 @@ func copySliceOfRules(rr []*RuleHandle) []*RuleHandle {
 	return ns
 }
 
+// Name returns the name of the rule.
+func (r *RuleHandle) Name() string {
+	return r.name
+}
+
 func (r *RuleHandle) NoLoop() *RuleHandle {
 	r.noLoop = true
+	ruleRevision.Add(1)
 
 	return r
 }
@@ -98,6 +120,7 @@ func (r *RuleHandle) Deactivate(rules ...string) *RuleHandle {
 	}
 
 	r.retracts = uniq(append(r.retracts, rules...))
+	ruleRevision.Add(1)
 
 	return r
 }
@@ -108,42 +131,49 @@ func (r *RuleHandle) Activate(rules ...string) *RuleHandle {
 	}
 
 	r.inserts = uniq(append(r.inserts, rules...))
+	ruleRevision.Add(1)
 
 	return r
 }
 
 func (r *RuleHandle) Unit(unit string) *RuleHandle {
 	r.unit = unit
+	ruleRevision.Add(1)
 
 	return r
 }
 
 func (r *RuleHandle) ActivationUnit(activationUnit string) *RuleHandle {
 	r.activationUnit = &activationUnit
+	ruleRevision.Add(1)
 
 	return r
 }
 
 func (r *RuleHandle) DeactivateUnits(units ...string) *RuleHandle {
 	r.deactivateUnits = uniq(append(r.deactivateUnits, units...))
+	ruleRevision.Add(1)
 
 	return r
 }
 
 func (r *RuleHandle) ActivateUnits(units ...string) *RuleHandle {
 	r.activateUnits = uniq(append(r.activateUnits, units...))
+	ruleRevision.Add(1)
 
 	return r
 }
 
 func (r *RuleHandle) SetFocus(units ...string) *RuleHandle {
 	r.focusUnits = uniq(append(r.focusUnits, units...))
+	ruleRevision.Add(1)
 
 	return r
 }
 
 func (r *RuleHandle) Salience(salience int) *RuleHandle {
 	r.salience = salience
+	ruleRevision.Add(1)
 
 	return r
 }
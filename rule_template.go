@@ -0,0 +1,251 @@
+package krools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RuleTemplate stamps out rules of the same shape from parameters of type P. Names of rules are made by a
+// text/template executed with parameters, so every parameter set must give a distinct name:
+//
+//	t := krools.NewRuleTemplate("discount {{.Tier}}", func(p Tier) krools.ConditionFn {
+//		return func(ctx krools.Context) (bool, error) { ... p.Threshold ... }
+//	}, func(p Tier) krools.ActionFn { ... })
+//
+// Parameters may be Go values or may be read with ParamsFromCSV and ParamsFromJSON.
+type RuleTemplate[P any] struct {
+	name      *template.Template
+	condition func(p P) ConditionFn
+	action    func(p P) ActionFn
+	salience  func(p P) int
+	unit      func(p P) string
+	configure []func(p P, r *RuleHandle)
+}
+
+// NewRuleTemplate creates a template of rules. It panics if the name is not a valid text/template.
+func NewRuleTemplate[P any](name string, condition func(p P) ConditionFn, action func(p P) ActionFn) *RuleTemplate[P] {
+	t, err := template.New("name").Option("missingkey=error").Parse(name)
+	if err != nil {
+		panic(fmt.Sprintf("invalid rule name template: %s", err))
+	}
+
+	return &RuleTemplate[P]{name: t, condition: condition, action: action}
+}
+
+// Salience sets salience of rules made from parameters.
+func (t *RuleTemplate[P]) Salience(fn func(p P) int) *RuleTemplate[P] {
+	t.salience = fn
+
+	return t
+}
+
+// Unit sets units of rules made from parameters.
+func (t *RuleTemplate[P]) Unit(fn func(p P) string) *RuleTemplate[P] {
+	t.unit = fn
+
+	return t
+}
+
+// Configure adds a function setting other attributes of stamped rules like NoLoop or Deactivate.
+func (t *RuleTemplate[P]) Configure(fn func(p P, r *RuleHandle)) *RuleTemplate[P] {
+	t.configure = append(t.configure, fn)
+
+	return t
+}
+
+// Rule stamps out the rule of the parameters.
+func (t *RuleTemplate[P]) Rule(p P) (*RuleHandle, error) {
+	var name strings.Builder
+
+	if err := t.name.Execute(&name, p); err != nil {
+		return nil, fmt.Errorf("execute name template of rule: %w", err)
+	}
+
+	r := NewInlineRule(name.String(), t.condition(p), t.action(p))
+
+	if t.salience != nil {
+		r.Salience(t.salience(p))
+	}
+
+	if t.unit != nil {
+		r.Unit(t.unit(p))
+	}
+
+	for _, fn := range t.configure {
+		fn(p, r)
+	}
+
+	return r, nil
+}
+
+// Rules stamps out rules of all parameters, names of rules must be distinct.
+func (t *RuleTemplate[P]) Rules(params ...P) ([]*RuleHandle, error) {
+	rules := make([]*RuleHandle, 0, len(params))
+	names := make(map[string]int, len(params))
+
+	for i, p := range params {
+		r, err := t.Rule(p)
+		if err != nil {
+			return nil, fmt.Errorf("stamp rule of parameters %d: %w", i, err)
+		}
+
+		if j, ok := names[r.name]; ok {
+			return nil, fmt.Errorf("parameters %d and %d give the same rule name '%s'", j, i, r.name)
+		}
+
+		names[r.name] = i
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// AddTo stamps out rules of all parameters and adds them to the knowledge base.
+func (t *RuleTemplate[P]) AddTo(k *KnowledgeBase, params ...P) error {
+	rules, err := t.Rules(params...)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		k.Add(r)
+	}
+
+	return nil
+}
+
+// ParamsFromJSON decodes a JSON array of parameters.
+func ParamsFromJSON[P any](r io.Reader) ([]P, error) {
+	var params []P
+
+	if err := json.NewDecoder(r).Decode(&params); err != nil {
+		return nil, fmt.Errorf("decode parameters: %w", err)
+	}
+
+	return params, nil
+}
+
+// ParamsFromCSV reads parameters from CSV where the first row has names of fields of P, or values of their csv tags.
+// Fields may be strings, bools, ints, uints, floats and time.Duration, empty cells leave zero values. P must be a
+// struct.
+func ParamsFromCSV[P any](r io.Reader) ([]P, error) {
+	t := reflect.TypeFor[P]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parameters of type '%s' are not a struct", t)
+	}
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header of parameters: %w", err)
+	}
+
+	fields := make([][]int, len(header))
+
+	for i, name := range header {
+		f, ok := csvField(t, strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("parameters have no field for column '%s'", name)
+		}
+
+		fields[i] = f.Index
+	}
+
+	var params []P
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return params, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read parameters: %w", err)
+		}
+
+		var p P
+		v := reflect.ValueOf(&p).Elem()
+
+		for i, cell := range record {
+			if err = parseCSVValue(v.FieldByIndex(fields[i]), strings.TrimSpace(cell)); err != nil {
+				return nil, fmt.Errorf("parse column '%s' of line %d: %w", header[i], line, err)
+			}
+		}
+
+		params = append(params, p)
+	}
+}
+
+func csvField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.IsExported() && (f.Tag.Get("csv") == name || f.Tag.Get("csv") == "" && f.Name == name) {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func parseCSVValue(v reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+
+	if _, ok := v.Interface().(time.Duration); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("type %s is not supported", v.Type())
+	}
+
+	return nil
+}
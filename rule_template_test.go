@@ -0,0 +1,127 @@
+package krools_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Threshold struct {
+	Tier     string        `json:"tier"`
+	Min      int           `json:"min" csv:"min"`
+	Percent  int           `json:"percent" csv:"percent"`
+	Priority int           `json:"priority" csv:"priority"`
+	Delay    time.Duration `json:"-" csv:"delay"`
+}
+
+type TieredOrder struct {
+	total int
+}
+
+func discountTemplate() *krools.RuleTemplate[Threshold] {
+	return krools.NewRuleTemplate("discount {{.Tier}} over {{.Min}}", func(p Threshold) krools.ConditionFn {
+		return func(ctx krools.Context) (bool, error) {
+			o, ok := krools.Get[TieredOrder](ctx)
+			return ok && o.total >= p.Min && !krools.Has[Discount](ctx), nil
+		}
+	}, func(p Threshold) krools.ActionFn {
+		return func(ctx krools.Context) error {
+			krools.Set(ctx, Discount{percent: p.Percent})
+			return nil
+		}
+	}).Salience(func(p Threshold) int {
+		return p.Priority
+	}).Unit(func(p Threshold) string {
+		return "discounts " + p.Tier
+	}).Configure(func(p Threshold, r *krools.RuleHandle) {
+		r.ActivationUnit("discount " + p.Tier)
+	})
+}
+
+func TestRuleTemplate(t *testing.T) {
+	params, err := krools.ParamsFromCSV[Threshold](strings.NewReader(`Tier, min, percent, priority, delay
+gold, 1000, 15, 2, 1m
+gold, 100, 5, 1,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(params) != 2 || params[0].Delay != time.Minute || params[1].Tier != "gold" {
+		t.Fatalf("unexpected parameters: %+v", params)
+	}
+
+	k := krools.NewKnowledgeBase("template base")
+
+	if err = discountTemplate().AddTo(k, params...); err != nil {
+		t.Fatal(err)
+	}
+
+	s := k.NewSession()
+	krools.Set(s, TieredOrder{total: 5000})
+
+	if err = s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := krools.MustGet[Discount](s); d.percent != 15 {
+		t.Errorf("expected 15, got %d", d.percent)
+	}
+
+	rules, err := discountTemplate().Rules(params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rules[1].Name() != "discount gold over 100" {
+		t.Errorf("unexpected name %s", rules[1].Name())
+	}
+}
+
+func TestRuleTemplate_JSON(t *testing.T) {
+	params, err := krools.ParamsFromJSON[Threshold](strings.NewReader(`[
+		{"tier": "silver", "min": 10, "percent": 1},
+		{"tier": "silver", "min": 10, "percent": 2}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = discountTemplate().Rules(params...); err == nil || !strings.Contains(err.Error(), "the same rule name 'discount silver over 10'") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRuleTemplate_Errors(t *testing.T) {
+	if _, err := krools.ParamsFromCSV[Threshold](strings.NewReader("Tier, unknown\n")); err == nil {
+		t.Error("expected error for unknown column")
+	}
+
+	if _, err := krools.ParamsFromCSV[Threshold](strings.NewReader("min\nmany\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := krools.ParamsFromCSV[map[string]string](strings.NewReader("min\n")); err == nil {
+		t.Error("expected error for parameters not being a struct")
+	}
+
+	_, err := krools.NewRuleTemplate("{{.Missing}}", func(p map[string]int) krools.ConditionFn {
+		return nil
+	}, func(p map[string]int) krools.ActionFn {
+		return nil
+	}).Rule(map[string]int{})
+	if err == nil {
+		t.Error("expected error for missing key")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid template")
+		}
+	}()
+
+	krools.NewRuleTemplate[Threshold]("{{.Tier", nil, nil)
+}
@@ -4,36 +4,88 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Session is a working memory with rules of a knowledge base to fire against it.
+//
+// It's safe to use working memory methods of a session from many goroutines, also while rules are firing. Rules
+// hold the session while a cycle of evaluating conditions and executing actions runs, meanwhile writes (Set, Delete,
+// Insert, Update, Retract and Clear) from other goroutines are queued and applied before the next cycle, and reads
+// wait for the cycle to end. A write happens before every operation on the session started after the write
+// returned, so the write is visible to them. Pointers returned by Handle, Fact and Facts give access to facts
+// bypassing the session, so they must not be used while rules are firing. Rules must access working memory through
+// the Context only, using the session from rules deadlocks. Setters of the session configuration must not be called
+// while rules are firing.
 type Session struct {
-	*structTypeContainer
+	mu        sync.Mutex
+	memory    *structTypeContainer
+	pendingMu sync.Mutex
+	pending   []func() bool
+	wake      chan struct{}
+	halt      atomic.Bool
+	firing    atomic.Bool
 
 	knowledgeBaseName string
+	version           uint64
 	units             map[string][]*RuleHandle
 	unitsOrder        []string
-	activationUnits   map[string][]*RuleHandle
 	deactivatedUnits  []string
+	unitRuleNames     map[string][]string
+	activationUnits   map[string][]string
 	maxReevaluations  int
+	trackDependencies bool
+	tracing           bool
+	tracer            Tracer
+	logger            *slog.Logger
+	collectMetrics    bool
+	metricsExporter   MetricsExporter
+	metrics           Metrics
+	resolver          ConflictResolver
+
+	network *rete
+	rete    *reteMemory
+	tms     *truthMaintenance
+	expiry  *eventExpiry
+	clock   Clock
+	timers  *timers
+
+	listeners *listeners
 }
 
-func newSession(
-	knowledgeBaseName string,
-	units map[string][]*RuleHandle,
-	unitsOrder []string,
-	activationUnits map[string][]*RuleHandle,
-	deactivatedUnits []string,
-) *Session {
-	return &Session{
-		structTypeContainer: newStructTypeContainer(),
-
+func newSession(knowledgeBaseName string, rules *snapshot, resolver ConflictResolver, listeners *listeners) *Session {
+	s := &Session{
 		knowledgeBaseName: knowledgeBaseName,
-		units:             units,
-		unitsOrder:        unitsOrder,
-		activationUnits:   activationUnits,
-		deactivatedUnits:  deactivatedUnits,
+		units:             rules.units,
+		unitsOrder:        rules.unitsOrder,
+		deactivatedUnits:  rules.deactivatedUnits,
+		unitRuleNames:     rules.unitRuleNames,
+		activationUnits:   rules.activationUnitRuleNames,
 		maxReevaluations:  65535,
+		resolver:          resolver,
+
+		network: rules.network,
+		memory:  newStructTypeContainer(),
+		wake:    make(chan struct{}, 1),
+		tms:     newTruthMaintenance(),
+		expiry:  newEventExpiry(rules.expirations),
+		clock:   RealClock(),
+		timers:  newTimers(),
+
+		listeners: listeners,
+		logger:    discardLogger,
 	}
+
+	s.memory.listeners = listeners
+
+	if len(s.network.terminals) > 0 {
+		s.rete = newReteMemory(s.network, s.memory)
+	}
+
+	return s
 }
 
 func (s *Session) SetMaxReevaluations(v int) *Session {
@@ -42,6 +94,31 @@ func (s *Session) SetMaxReevaluations(v int) *Session {
 	return s
 }
 
+// SetDependencyTracking turns on or off tracking of types read by conditions. When it's on a condition that was not
+// satisfied is not evaluated again until some of types it read from the Context is changed. Conditions that call
+// Context.Context, use locals or don't read working memory at all are always evaluated. It's off by default since a
+// condition may depend on anything else besides working memory, like a variable set by an action, and then it would
+// not be evaluated again; turn it on only if conditions depend on working memory only.
+func (s *Session) SetDependencyTracking(enabled bool) *Session {
+	s.trackDependencies = enabled
+
+	return s
+}
+
+// SetConflictResolver sets the resolver that orders rules applicable at the same time.
+func (s *Session) SetConflictResolver(resolver ConflictResolver) *Session {
+	s.resolver = resolver
+
+	return s
+}
+
+// SetClock sets the clock of the session.
+func (s *Session) SetClock(clock Clock) *Session {
+	s.clock = clock
+
+	return s
+}
+
 func (s *Session) SetFocus(units ...string) *Session {
 	s.unitsOrder = uniq(append(units, s.unitsOrder...))
 
@@ -54,136 +131,472 @@ func (s *Session) SetDeactivatedUnits(units ...string) *Session {
 	return s
 }
 
-func (s *Session) Clear() {
-	s.structTypeContainer = newStructTypeContainer()
-}
-
+// FireAllRules fires rules until there are no applicable rules left. Options may be filters of rules and a single
+// dispatcher which manages execution itself.
 func (s *Session) FireAllRules(ctx context.Context, options ...any) error {
-	var ruleFilters []Filter
+	filters, dispatcher, err := fireOptions(options)
+	if err != nil {
+		return err
+	}
 
-	for _, option := range options {
-		if v, ok := option.(Filter); ok {
-			ruleFilters = append(ruleFilters, v)
-		}
+	s.halt.Store(false)
+
+	fireAllRules := func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.apply()
+
+		return s.fire(s.newFireContext(ctx), filters...)
 	}
 
-	fc := &fireContext{
-		ctx:                 ctx,
-		structTypeContainer: s.structTypeContainer,
+	if dispatcher != nil {
+		return dispatcher.Dispatch(ctx, s, fireAllRules)
 	}
 
-	return s.fire(fc, ruleFilters...)
+	return fireAllRules(ctx)
 }
 
-func (s *Session) fire(ctx *fireContext, ruleFilters ...Filter) error {
-	ret := newRetracting()
-	flow := newFlowController(ret, s.units, s.unitsOrder, s.deactivatedUnits)
+// FireUntilHalt fires all rules and then waits for facts written by other goroutines or for timers of rules to fire
+// all rules again. It returns nil once Halt is called or an action calls Context.Halt, or the error of the context
+// when it's done.
+func (s *Session) FireUntilHalt(ctx context.Context, options ...any) error {
+	filters, dispatcher, err := fireOptions(options)
+	if err != nil {
+		return err
+	}
+
+	if dispatcher != nil {
+		return errors.New("dispatcher is not supported by fire until halt")
+	}
 
-	var reevaluations int
+	s.halt.Store(false)
+
+	var (
+		timer   <-chan time.Time
+		timerAt time.Time
+	)
+
+	for {
+		fc := s.newFireContext(ctx)
+
+		s.mu.Lock()
+		s.apply()
+		err = s.fire(fc, filters...)
+		s.mu.Unlock()
 
-	for flow.more() {
-		applicable, err := s.applicableRules(ctx, flow.rules(), ret, false, ruleFilters...)
 		if err != nil {
 			return err
 		}
 
-		for len(applicable) > 0 {
-			for _, rule := range applicable {
-				if err = s.executeAction(ctx, rule, ret, flow); err != nil {
-					return err
-				}
-			}
+		if fc.halted {
+			return nil
+		}
 
-			applicable, err = s.applicableRules(ctx, flow.rules(), ret, true, ruleFilters...)
-			if err != nil {
-				return err
-			}
+		// The pending timer is kept unless the next timer is due earlier, so the clock doesn't pile up waiters every
+		// time the session is woken up by writes.
+		s.mu.Lock()
+		if next := s.timers.next(); !next.IsZero() && (timer == nil || next.Before(timerAt)) {
+			timer, timerAt = s.clock.After(next.Sub(s.clock.Now())), next
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.wake:
+		case <-timer:
+			timer = nil
+		}
+	}
+}
+
+// Halt stops firing rules after the action being executed, or stops FireUntilHalt waiting for changes. It doesn't
+// affect fires started after it, so a halt made while the session is idle is dropped.
+func (s *Session) Halt() {
+	s.halt.Store(true)
+	s.signal()
+}
+
+// signal wakes up FireUntilHalt waiting for changes.
+func (s *Session) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// halted reports if firing must be stopped, it consumes a halt request.
+func (s *Session) halted(ctx *fireContext) bool {
+	if s.halt.CompareAndSwap(true, false) {
+		ctx.halted = true
+	}
+
+	return ctx.halted
+}
 
-			reevaluations++
-			if reevaluations > s.maxReevaluations {
-				return errors.New("too much reevaluations")
+func fireOptions(options []any) ([]Filter, Dispatcher, error) {
+	var (
+		filters    []Filter
+		dispatcher Dispatcher
+	)
+
+	for i, option := range options {
+		switch v := option.(type) {
+		case Filter:
+			filters = append(filters, v)
+		case Dispatcher:
+			if dispatcher != nil {
+				return nil, nil, fmt.Errorf("option %d is the second dispatcher", i)
 			}
+
+			dispatcher = v
+		default:
+			return nil, nil, fmt.Errorf("option %d of type %T is not supported", i, option)
+		}
+	}
+
+	return filters, dispatcher, nil
+}
+
+func (s *Session) newFireContext(ctx context.Context) *fireContext {
+	return &fireContext{
+		ctx:                 ctx,
+		structTypeContainer: s.memory,
+		rete:                s.rete,
+		tms:                 s.tms,
+		expiry:              s.expiry,
+		clock:               s.clock,
+		logger:              s.logger,
+		knowledgeBaseName:   s.knowledgeBaseName,
+		locals:              make(map[*RuleHandle]*structTypeContainer),
+		supports:            make(map[*RuleHandle]support),
+	}
+}
+
+func (s *Session) fire(ctx *fireContext, ruleFilters ...Filter) (err error) {
+	s.firing.Store(true)
+	defer s.firing.Store(false)
+
+	end := s.startSpan(ctx, "krools.fire", Attribute{Key: AttributeKnowledgeBase, Value: s.knowledgeBaseName})
+	defer func() { end(err) }()
+
+	s.startMetrics(ctx)
+	defer s.finishMetrics(ctx)
+
+	ret := newRetracting()
+	flow := newFlowController(ret, s.units, s.unitRuleNames, s.unitsOrder, s.deactivatedUnits)
+	deps := newDependencies()
+	agenda := newAgenda(s.resolver, s.listeners)
+
+	s.listeners.trace = nil
+	if s.tracing {
+		s.listeners.trace = &ExecutionTrace{}
+	}
+
+	s.expiry.expire(s.memory, s.clock.Now())
+
+	if err = s.maintainTruth(ctx); err != nil {
+		return err
+	}
+
+	s.debug(ctx, "fire started", "units", flow.unitsOrder)
+
+	for !s.halted(ctx) && flow.more() {
+		if err = s.fireUnit(ctx, flow, ret, deps, agenda, ruleFilters); err != nil {
+			return err
 		}
 	}
 
+	s.expiry.expire(s.memory, s.clock.Now())
+
+	s.debug(ctx, "fire finished", "cycles", ctx.stats.Cycles, "executions", ctx.stats.Executions)
+
 	return nil
 }
 
+// fireUnit fires rules of the current unit until there are no applicable rules left.
+func (s *Session) fireUnit(
+	ctx *fireContext,
+	flow *flowController,
+	ret *retracting,
+	deps *dependencies,
+	agenda *agenda,
+	filters []Filter,
+) (err error) {
+	end := s.startSpan(ctx, "krools.unit",
+		Attribute{Key: AttributeKnowledgeBase, Value: s.knowledgeBaseName},
+		Attribute{Key: AttributeUnit, Value: flow.unit()})
+	defer func() { end(err) }()
+
+	for reevaluate := false; ; reevaluate = true {
+		done, err := s.fireCycle(ctx, flow, ret, deps, agenda, reevaluate, filters)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// fireCycle looks for applicable rules and executes them. It reports if there were no applicable rules or firing
+// was halted.
+func (s *Session) fireCycle(
+	ctx *fireContext,
+	flow *flowController,
+	ret *retracting,
+	deps *dependencies,
+	agenda *agenda,
+	reevaluate bool,
+	filters []Filter,
+) (done bool, err error) {
+	end := s.startSpan(ctx, "krools.cycle",
+		Attribute{Key: AttributeKnowledgeBase, Value: s.knowledgeBaseName},
+		Attribute{Key: AttributeUnit, Value: flow.unit()},
+		Attribute{Key: AttributeCycle, Value: ctx.stats.Cycles + 1})
+	defer func() { end(err) }()
+
+	if reevaluate {
+		s.sync()
+		s.expiry.expire(s.memory, s.clock.Now())
+
+		if err = s.maintainTruth(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	applicable, err := s.applicableRules(ctx, flow, ret, deps, agenda, reevaluate, filters...)
+	if err != nil {
+		return false, err
+	}
+
+	if s.logger.Enabled(ctx.ctx, slog.LevelDebug) {
+		names := make([]string, 0, len(applicable))
+		for _, rule := range applicable {
+			names = append(names, rule.name)
+		}
+
+		s.debug(ctx, "cycle", "unit", flow.unit(), "cycle", ctx.stats.Cycles, "applicable", names)
+	}
+
+	if reevaluate {
+		ctx.stats.Reevaluations++
+		if ctx.stats.Reevaluations > s.maxReevaluations {
+			return false, errors.New("too much reevaluations")
+		}
+	}
+
+	for _, rule := range applicable {
+		if ret.isRetracted(rule.name) {
+			s.listeners.trace.retracted(rule, ret.cause(rule.name))
+			agenda.cancel(rule)
+			continue
+		}
+
+		if err = s.executeAction(ctx, rule, ret, flow); err != nil {
+			return false, err
+		}
+
+		agenda.fired(rule)
+
+		if s.halted(ctx) {
+			return true, nil
+		}
+	}
+
+	return len(applicable) == 0, nil
+}
+
 func (s *Session) applicableRules(
 	ctx *fireContext,
-	rules []*RuleHandle,
+	flow *flowController,
 	ret *retracting,
+	deps *dependencies,
+	agenda *agenda,
 	discardNoLoop bool,
 	filters ...Filter,
 ) ([]*RuleHandle, error) {
-	var applicable []*RuleHandle
+	var applicable []*Activation
+
+	ctx.stats.Cycles++
+	trace := s.listeners.trace
+	trace.cycle(flow.unit())
 
 loop:
-	for _, rule := range rules {
+	for _, rule := range flow.rules() {
 		if ret.isRetracted(rule.name) {
+			trace.retracted(rule, ret.cause(rule.name))
 			continue
 		}
 
 		if discardNoLoop && rule.noLoop {
+			trace.step(rule, ReasonNoLoop, nil)
 			continue
 		}
 
 		for i, filter := range filters {
-			ok, err := filter.IsSatisfiedBy(ctx.Context(), rule)
+			ok, err := filter.IsSatisfiedBy(ctx.ctx, rule)
 			if err != nil {
 				return nil, fmt.Errorf("verify that rule '%s' of knowledge base '%s' is satisfies filter %d: %w", rule.name, s.knowledgeBaseName, i, err)
 			}
 
 			if !ok {
+				trace.step(rule, ReasonFiltered, nil)
 				continue loop
 			}
 		}
 
-		satisfied := true
+		var (
+			satisfied   = true
+			recency     uint64
+			specificity int
+		)
 
 		if rule.condition != nil {
-			var err error
-			err = func() error {
-				ctx.rule = rule
-				defer func() { ctx.rule = nil }()
-
-				satisfied, err = rule.condition.When(ctx)
-				if err != nil {
-					return fmt.Errorf("verify that condition of rule '%s' of knowledge base '%s' is satisfied by fire context: %w", rule.name, s.knowledgeBaseName, err)
-				}
+			if s.trackDependencies && deps.unchanged(rule, ctx.structTypeContainer) {
+				trace.step(rule, ReasonConditionFalse, nil)
+				continue
+			}
 
-				return nil
-			}()
+			var (
+				reads    map[string]struct{}
+				volatile bool
+				err      error
+			)
 
+			satisfied, reads, volatile, err = s.evaluate(ctx, rule)
 			if err != nil {
+				trace.step(rule, ReasonConditionError, err)
 				return nil, err
 			}
+
+			if s.trackDependencies && !satisfied && !volatile {
+				deps.remember(rule, reads, ctx.structTypeContainer)
+			}
+
+			for key := range reads {
+				recency = max(recency, ctx.versions[key])
+			}
+
+			specificity = len(reads)
+			if c, ok := rule.condition.(*PatternCondition); ok {
+				specificity = c.specificity()
+			}
+
+			ctx.supports[rule] = support{reads: reads, volatile: volatile}
 		}
 
-		if satisfied {
-			applicable = append(applicable, rule)
+		if rule.timer != nil {
+			if !satisfied {
+				s.timers.disarm(rule)
+			} else if !s.timers.due(rule, s.clock.Now()) {
+				trace.step(rule, ReasonTimerPending, nil)
+				continue
+			}
 		}
+
+		if !satisfied {
+			trace.step(rule, ReasonConditionFalse, nil)
+		} else {
+			trace.step(rule, ReasonMatched, nil)
+			s.debug(ctx, "rule matched", "rule", rule.name, "unit", rule.unit)
+			ctx.metrics.matched(rule)
+
+			applicable = append(applicable, agenda.activate(rule, recency, specificity))
+		}
+	}
+
+	agenda.retain(applicable)
+
+	return agenda.resolve(applicable), nil
+}
+
+// evaluate evaluates the condition of the rule and returns types of facts it read and if it depends on anything
+// besides working memory.
+func (s *Session) evaluate(ctx *fireContext, rule *RuleHandle) (bool, map[string]struct{}, bool, error) {
+	ctx.stats.Evaluations++
+
+	s.listeners.agendaEvent(func(l AgendaEventListener) { l.BeforeConditionEvaluated(ConditionEvent{Rule: rule}) })
+
+	end := s.startSpan(ctx, "krools.condition",
+		Attribute{Key: AttributeKnowledgeBase, Value: s.knowledgeBaseName},
+		Attribute{Key: AttributeUnit, Value: rule.unit},
+		Attribute{Key: AttributeRule, Value: rule.name})
+
+	started := ctx.metrics.now()
+	satisfied, reads, volatile, err := s.check(ctx, rule)
+	ctx.metrics.evaluated(rule, started, err)
+	end(err)
+
+	s.listeners.agendaEvent(func(l AgendaEventListener) {
+		l.AfterConditionEvaluated(ConditionEvent{Rule: rule, Satisfied: satisfied, Err: err})
+	})
+
+	if err != nil {
+		return false, nil, false, fmt.Errorf("verify that condition of rule '%s' of knowledge base '%s' is satisfied by fire context: %w", rule.name, s.knowledgeBaseName, err)
 	}
 
-	sortRulesConsiderSalience(applicable)
+	return satisfied, reads, volatile, nil
+}
+
+// check evaluates the condition of the rule like evaluate but silently: listeners, tracer, metrics and statistics
+// don't see it.
+func (s *Session) check(ctx *fireContext, rule *RuleHandle) (bool, map[string]struct{}, bool, error) {
+	ctx.rule = rule
+	ctx.reads = make(map[string]struct{})
+	ctx.volatile = false
+	defer func() {
+		ctx.rule = nil
+		ctx.reads = nil
+	}()
+
+	satisfied, err := true, error(nil)
+	if rule.condition != nil {
+		satisfied, err = rule.condition.When(ctx)
+	}
 
-	return applicable, nil
+	return satisfied, ctx.reads, ctx.volatile, err
 }
 
 func (s *Session) executeAction(ctx *fireContext, rule *RuleHandle, ret *retracting, flow *flowController) error {
-	if ret.isRetracted(rule.name) {
-		return nil
+	ctx.stats.Executions++
+	ctx.metrics.fired(rule)
+
+	if rule.timer != nil {
+		s.timers.fired(rule, s.clock.Now())
 	}
 
+	s.listeners.trace.step(rule, ReasonFired, nil)
+
 	if rule.action != nil {
 		if err := func() error {
 			ctx.rule = rule
+			ctx.acting = true
+			s.listeners.rule = rule
 			defer func() {
-				ctx.rule.locals = newStructTypeContainer()
+				delete(ctx.locals, ctx.rule)
 				ctx.rule = nil
+				ctx.acting = false
+				s.listeners.rule = nil
 			}()
 
-			if err := rule.action.Then(ctx); err != nil {
+			end := s.startSpan(ctx, "krools.action",
+				Attribute{Key: AttributeKnowledgeBase, Value: s.knowledgeBaseName},
+				Attribute{Key: AttributeUnit, Value: rule.unit},
+				Attribute{Key: AttributeRule, Value: rule.name},
+				Attribute{Key: AttributeSalience, Value: rule.salience})
+
+			s.listeners.agendaEvent(func(l AgendaEventListener) { l.BeforeActionExecuted(ActionEvent{Rule: rule}) })
+
+			started := ctx.metrics.now()
+			err := rule.action.Then(ctx)
+			ctx.metrics.executed(rule, started, err)
+			end(err)
+			if err != nil && s.listeners.trace != nil {
+				s.listeners.trace.last().Err = err
+			}
+
+			s.listeners.agendaEvent(func(l AgendaEventListener) { l.AfterActionExecuted(ActionEvent{Rule: rule, Err: err}) })
+
+			if err != nil {
 				return fmt.Errorf("execute action of rule '%s' of knowledge base '%s': %w", rule.name, s.knowledgeBaseName, err)
 			}
 
@@ -193,21 +606,55 @@ func (s *Session) executeAction(ctx *fireContext, rule *RuleHandle, ret *retract
 		}
 	}
 
-	ret.add(rule.retracts...)
-	flow.deactivateUnits(rule.deactivateUnits...)
-	flow.activateUnits(rule.activateUnits...)
+	s.retract(ctx, rule, ret, rule.retracts)
+	deactivateUnits := func(units ...string) { flow.deactivateUnits(rule.name, units...) }
+
+	s.changeUnits(ctx, "units deactivated", rule, rule.deactivateUnits, deactivateUnits,
+		AgendaEventListener.BeforeUnitsDeactivated, AgendaEventListener.AfterUnitsDeactivated)
+	s.changeUnits(ctx, "units activated", rule, rule.activateUnits, flow.activateUnits,
+		AgendaEventListener.BeforeUnitsActivated, AgendaEventListener.AfterUnitsActivated)
 	ret.reject(rule.inserts...)
-	flow.setFocus(rule.focusUnits...)
+	s.changeUnits(ctx, "focus set", rule, rule.focusUnits, flow.setFocus,
+		AgendaEventListener.BeforeUnitsFocused, AgendaEventListener.AfterUnitsFocused)
 
 	if rule.activationUnit != nil {
-		var names []string
+		s.retract(ctx, rule, ret, reject(s.activationUnits[*rule.activationUnit], rule.name))
+	}
 
-		for _, r := range s.activationUnits[*rule.activationUnit] {
-			names = append(names, r.name)
-		}
+	return nil
+}
 
-		ret.add(reject(names, rule.name)...)
+func (s *Session) retract(ctx *fireContext, rule *RuleHandle, ret *retracting, rules []string) {
+	if len(rules) == 0 {
+		return
 	}
 
-	return nil
+	s.debug(ctx, "rules retracted", "rule", rule.name, "retracted", rules)
+
+	e := RetractionEvent{Rule: rule, Rules: rules}
+
+	s.listeners.agendaEvent(func(l AgendaEventListener) { l.BeforeRulesRetracted(e) })
+	ret.add(retraction{by: rule.name}, rules...)
+	s.listeners.agendaEvent(func(l AgendaEventListener) { l.AfterRulesRetracted(e) })
+}
+
+func (s *Session) changeUnits(
+	ctx *fireContext,
+	msg string,
+	rule *RuleHandle,
+	units []string,
+	change func(units ...string),
+	before, after func(l AgendaEventListener, e UnitEvent),
+) {
+	if len(units) == 0 {
+		return
+	}
+
+	s.debug(ctx, msg, "rule", rule.name, "units", units)
+
+	e := UnitEvent{Rule: rule, Units: units}
+
+	s.listeners.agendaEvent(func(l AgendaEventListener) { before(l, e) })
+	change(units...)
+	s.listeners.agendaEvent(func(l AgendaEventListener) { after(l, e) })
 }
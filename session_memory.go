@@ -0,0 +1,214 @@
+package krools
+
+import (
+	"iter"
+	"time"
+)
+
+func (s *Session) Set(v any) {
+	s.setKey(structValue(v))
+}
+
+func (s *Session) Get(v any) bool {
+	var ok bool
+	s.read(func() { ok = s.memory.Get(v) })
+
+	return ok
+}
+
+func (s *Session) Handle(v any) any {
+	return s.handleKey(structKey(v))
+}
+
+func (s *Session) HasNot(v any) bool {
+	return s.handleKey(structKey(v)) == nil
+}
+
+func (s *Session) Delete(v any) {
+	s.deleteKey(structKey(v))
+}
+
+// Insert inserts the fact. The handle is returned at once even if the fact is queued because rules are firing.
+func (s *Session) Insert(v any) FactHandle {
+	key, v := structValue(v)
+	h := nextFactHandle()
+
+	s.write(func() bool {
+		s.memory.insert(h, key, v)
+		return true
+	})
+
+	return h
+}
+
+// Update updates the fact. If the update is queued because rules are firing it returns true, and the update is
+// ignored when it's applied if the fact is retracted by rules meanwhile.
+func (s *Session) Update(h FactHandle, v any) bool {
+	return s.write(func() bool { return s.memory.Update(h, v) })
+}
+
+// Retract retracts the fact. If the retraction is queued because rules are firing it returns true.
+func (s *Session) Retract(h FactHandle) bool {
+	return s.write(func() bool { return s.memory.Retract(h) })
+}
+
+func (s *Session) Fact(h FactHandle) any {
+	var v any
+	s.read(func() { v = s.memory.Fact(h) })
+
+	return v
+}
+
+// Facts iterates over facts inserted at the moment of the call.
+func (s *Session) Facts(v any) iter.Seq2[FactHandle, any] {
+	key := structKey(v)
+
+	type fact struct {
+		h FactHandle
+		v any
+	}
+
+	var facts []fact
+	s.read(func() {
+		for h, v := range s.memory.factsKey(key) {
+			facts = append(facts, fact{h: h, v: v})
+		}
+	})
+
+	return func(yield func(FactHandle, any) bool) {
+		for _, f := range facts {
+			if !yield(f.h, f.v) {
+				return
+			}
+		}
+	}
+}
+
+// InsertEvent inserts the event. The handle is returned at once even if the event is queued because rules are firing.
+func (s *Session) InsertEvent(v any, t EventTime) FactHandle {
+	key, v := structValue(v)
+	h := nextFactHandle()
+
+	s.write(func() bool {
+		s.memory.insertEvent(h, key, v, t)
+		return true
+	})
+
+	return h
+}
+
+func (s *Session) EventTime(h FactHandle) (EventTime, bool) {
+	var (
+		t  EventTime
+		ok bool
+	)
+
+	s.read(func() { t, ok = s.memory.EventTime(h) })
+
+	return t, ok
+}
+
+// Clear deletes all values and facts from working memory.
+func (s *Session) Clear() {
+	s.write(func() bool {
+		s.memory.clear()
+		s.tms.reset()
+		return true
+	})
+}
+
+func (s *Session) getKey(key string) any {
+	return s.handleKey(key)
+}
+
+func (s *Session) handleKey(key string) any {
+	var v any
+	s.read(func() { v = s.memory.handleKey(key) })
+
+	return v
+}
+
+func (s *Session) setKey(key string, v any) {
+	s.write(func() bool {
+		s.tms.unjustify(key)
+		s.memory.setKey(key, v)
+		return true
+	})
+}
+
+func (s *Session) deleteKey(key string) {
+	s.write(func() bool {
+		s.memory.deleteKey(key)
+		return true
+	})
+}
+
+func (s *Session) eventsKey(key string) []event {
+	var events []event
+	s.read(func() { events = s.memory.eventsKey(key) })
+
+	return events
+}
+
+func (s *Session) now() time.Time {
+	return s.clock.Now()
+}
+
+func (s *Session) window(key string, d time.Duration) {
+	s.read(func() { s.expiry.window(key, d) })
+}
+
+// read calls passed function holding the session after queued writes are applied.
+func (s *Session) read(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apply()
+	fn()
+}
+
+// write queues the write if rules are firing, otherwise it waits for the session and applies the write at once. It
+// returns the result of the write if it's applied, or true if it's queued.
+func (s *Session) write(fn func() bool) bool {
+	defer s.signal()
+
+	if !s.firing.Load() {
+		s.mu.Lock()
+
+		if !s.firing.Load() {
+			defer s.mu.Unlock()
+
+			s.apply()
+
+			return fn()
+		}
+
+		s.mu.Unlock()
+	}
+
+	s.pendingMu.Lock()
+	s.pending = append(s.pending, fn)
+	s.pendingMu.Unlock()
+
+	return true
+}
+
+// apply applies queued writes, the session must be held.
+func (s *Session) apply() {
+	s.pendingMu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.pendingMu.Unlock()
+
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+// sync lets other goroutines use the session between cycles and applies writes queued meanwhile.
+func (s *Session) sync() {
+	s.mu.Unlock()
+	s.mu.Lock()
+
+	s.apply()
+}
@@ -0,0 +1,141 @@
+package krools_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Ticket struct {
+	n int
+}
+
+func ticketsBase() *krools.KnowledgeBase {
+	return krools.NewKnowledgeBase("tickets base").
+		Add(krools.NewInlineRule("count tickets", func(ctx krools.Context) (bool, error) {
+			for range ctx.Facts(Ticket{}) {
+				return true, nil
+			}
+
+			return false, nil
+		}, func(ctx krools.Context) error {
+			c, _ := krools.Get[Counter](ctx)
+
+			for h := range ctx.Facts(Ticket{}) {
+				ctx.Retract(h)
+				c.n++
+			}
+
+			krools.Set(ctx, c)
+
+			return nil
+		}))
+}
+
+func TestSession_ConcurrentWrites(t *testing.T) {
+	s := ticketsBase().NewSession()
+
+	const producers, tickets = 4, 250
+
+	var wg sync.WaitGroup
+
+	for range producers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range tickets {
+				s.Insert(Ticket{n: i})
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+loop:
+	for {
+		select {
+		case <-done:
+			break loop
+		default:
+			if err := s.FireAllRules(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _ = krools.Get[Counter](s)
+		}
+	}
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := krools.MustGet[Counter](s); c.n != producers*tickets {
+		t.Fatalf("unexpected number of counted tickets: %d", c.n)
+	}
+}
+
+func TestSession_WriteVisibleAfterReturn(t *testing.T) {
+	s := ticketsBase().NewSession()
+
+	s.Insert(Ticket{})
+	s.Clear()
+
+	for range s.Facts(Ticket{}) {
+		t.Fatal("facts are not cleared")
+	}
+
+	h := s.Insert(Ticket{n: 1})
+	if !s.Update(h, Ticket{n: 2}) {
+		t.Fatal("fact is not updated")
+	}
+
+	if tk, ok := s.Fact(h).(*Ticket); !ok || tk.n != 2 {
+		t.Fatal("unexpected fact")
+	}
+}
+
+type blockingListener struct {
+	krools.DefaultWorkingMemoryEventListener
+
+	held, release chan struct{}
+}
+
+func (l *blockingListener) AfterFactSet(krools.WorkingMemoryEvent) {
+	close(l.held)
+	<-l.release
+}
+
+func TestSession_WriteWhileHeld(t *testing.T) {
+	l := &blockingListener{held: make(chan struct{}), release: make(chan struct{})}
+
+	s := ticketsBase().NewSession().AddWorkingMemoryEventListener(l)
+	missing := s.Insert(Ticket{n: 1})
+	s.Retract(missing)
+
+	go krools.Set(s, Counter{})
+	<-l.held
+
+	retracted := make(chan bool)
+	go func() { retracted <- s.Retract(missing) }()
+
+	select {
+	case <-retracted:
+		t.Fatal("write doesn't wait for the session held while rules are not firing")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(l.release)
+
+	if <-retracted {
+		t.Fatal("retracted a missing fact while rules are not firing")
+	}
+}
@@ -0,0 +1,73 @@
+package krools
+
+import (
+	"context"
+	"time"
+)
+
+// Stats are statistics of a single fire.
+type Stats struct {
+	// Cycles is the number of times applicable rules were looked for.
+	Cycles int
+	// Reevaluations is the number of cycles made after executing actions, it's limited by SetMaxReevaluations.
+	Reevaluations int
+	// Evaluations is the number of evaluated conditions.
+	Evaluations int
+	// Executions is the number of executed rules.
+	Executions int
+	// Duration is the time the fire took.
+	Duration time.Duration
+}
+
+// Result is the result of a stateless execution: the final working memory and statistics.
+type Result struct {
+	memory *structTypeContainer
+
+	Stats Stats
+}
+
+// Memory returns the final working memory, facts of it are read with generic functions like Get and Has.
+func (r *Result) Memory() WorkingMemory {
+	return r.memory
+}
+
+// Execute sets passed facts into a new session, fires all rules and returns the final working memory. Unlike
+// NewSession it doesn't copy rules for every call but shares a snapshot made once after rules are changed, either
+// added to the knowledge base or changed through their handles, so it's cheap and safe to call concurrently from many
+// goroutines as long as rules are not changed meanwhile.
+func (k *KnowledgeBase) Execute(ctx context.Context, facts ...any) (*Result, error) {
+	var s *Session
+
+	if k.built {
+		s = k.newSession(k.stateless)
+	} else {
+		k.mu.Lock()
+		if revision := ruleRevision.Load(); k.stateless == nil || k.statelessRevision != revision {
+			k.stateless = k.copy()
+			k.statelessRevision = revision
+		}
+
+		s = k.newSession(k.stateless)
+		k.mu.Unlock()
+	}
+
+	for _, fact := range facts {
+		s.Set(fact)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apply()
+
+	fc := s.newFireContext(ctx)
+	started := time.Now()
+
+	if err := s.fire(fc); err != nil {
+		return nil, err
+	}
+
+	fc.stats.Duration = time.Since(started)
+
+	return &Result{memory: s.memory, Stats: fc.stats}, nil
+}
@@ -0,0 +1,79 @@
+package krools_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+func TestExecute(t *testing.T) {
+	k := krools.NewKnowledgeBase("stateless base").
+		Add(krools.NewInlineRule("discount for large order", func(ctx krools.Context) (bool, error) {
+			o, ok := krools.Get[Order](ctx)
+			return ok && o.total > 100 && !krools.Has[Discount](ctx), nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Discount{percent: krools.MustGet[Order](ctx).id})
+			return nil
+		}))
+
+	var wg sync.WaitGroup
+
+	for i := range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			res, err := k.Execute(context.Background(), Order{id: i, total: 50 + i*10})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			d, ok := krools.Get[Discount](res.Memory())
+			if ok != (i > 5) || ok && d.percent != i {
+				t.Errorf("unexpected discount for order %d: %v", i, d)
+			}
+
+			if res.Stats.Cycles == 0 || res.Stats.Evaluations == 0 {
+				t.Errorf("unexpected stats: %+v", res.Stats)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestExecute_RuleChanged(t *testing.T) {
+	rule := krools.NewInlineRule("discount", func(ctx krools.Context) (bool, error) {
+		return !krools.Has[Discount](ctx), nil
+	}, func(ctx krools.Context) error {
+		krools.Set(ctx, Discount{percent: 5})
+		return nil
+	})
+
+	k := krools.NewKnowledgeBase("stateless base").
+		Add(rule).
+		Add(krools.NewInlineRule("vip discount", func(ctx krools.Context) (bool, error) {
+			d, ok := krools.Get[Discount](ctx)
+			return ok && d.percent != 10, nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Discount{percent: 10})
+			return nil
+		}))
+
+	for _, want := range []int{10, 5} {
+		res, err := k.Execute(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d := krools.MustGet[Discount](res.Memory()); d.percent != want {
+			t.Fatalf("expected discount %d, got %d", want, d.percent)
+		}
+
+		rule.Deactivate("vip discount")
+	}
+}
@@ -1,42 +1,57 @@
 package krools
 
 import (
+	"iter"
 	"reflect"
+	"slices"
+	"sync/atomic"
+	"time"
 )
 
-type structTypeContainer struct {
-	vals map[string]any
+// FactHandle identifies a single fact inserted into working memory with Insert. Handles are never reused, so a handle
+// of a retracted fact stays invalid.
+type FactHandle uint64
+
+var lastFactHandle atomic.Uint64
+
+func nextFactHandle() FactHandle {
+	return FactHandle(lastFactHandle.Add(1))
 }
 
-func newStructTypeContainer() *structTypeContainer {
-	return &structTypeContainer{vals: make(map[string]any)}
+type fact struct {
+	key string
+	v   any
 }
 
-// Set sets value in container, so passed value must be a struct or a pinter to struct.
-func (c *structTypeContainer) Set(v any) {
-	if v == nil {
-		panic("v cannot be nil")
-	}
+type structTypeContainer struct {
+	vals map[string]any
 
-	t := reflect.TypeOf(v)
+	// versions keeps the number of the last write for every type key, so readers can find out if something changed
+	// since they looked at it.
+	versions map[string]uint64
+	writes   uint64
 
-	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
-		t = t.Elem()
-	} else if t.Kind() == reflect.Struct {
-		ptr := reflect.New(t)
-		ptr.Elem().Set(reflect.ValueOf(v))
-		v = ptr.Interface()
-	} else {
-		panic("v must be a struct or a pointer to a struct")
-	}
+	facts   map[string][]FactHandle
+	handles map[FactHandle]*fact
+	events  map[FactHandle]EventTime
 
-	n := t.Name()
+	observers []func(key string, h FactHandle)
+	listeners *listeners
+}
 
-	if t.PkgPath() != "" {
-		n = t.PkgPath() + "." + n
+func newStructTypeContainer() *structTypeContainer {
+	return &structTypeContainer{
+		vals:     make(map[string]any),
+		versions: make(map[string]uint64),
+		facts:    make(map[string][]FactHandle),
+		handles:  make(map[FactHandle]*fact),
+		events:   make(map[FactHandle]EventTime),
 	}
+}
 
-	c.vals[n] = v
+// Set sets value in container, so passed value must be a struct or a pinter to struct.
+func (c *structTypeContainer) Set(v any) {
+	c.setKey(structValue(v))
 }
 
 // Get fills passed parameter with value if such value exists and returns true, or doesn't touch value and return false.
@@ -70,30 +85,249 @@ func (c *structTypeContainer) Get(v any) bool {
 
 // Handle returns a pointer to a value and if you can't convert it to desired type, so it's not found.
 func (c *structTypeContainer) Handle(v any) any {
-	if v == nil {
-		panic("v cannot be nil")
+	return c.handleKey(structKey(v))
+}
+
+// HasNot just checks that if passed value exists in the container and does not fill passed argument, so a struct or a
+// pointer to struct may be passed.
+func (c *structTypeContainer) HasNot(v any) bool {
+	_, exists := c.vals[structKey(v)]
+
+	return !exists
+}
+
+// Delete deletes value from container, so passed value must be a struct or a pinter to struct and concrete value
+// doesn't matter.
+func (c *structTypeContainer) Delete(v any) {
+	c.deleteKey(structKey(v))
+}
+
+// Insert adds passed value as one more fact of its type and returns a handle of the fact. Unlike Set it never
+// overwrites facts of the same type. Passed value must be a struct or a pointer to a struct.
+func (c *structTypeContainer) Insert(v any) FactHandle {
+	n, v := structValue(v)
+	h := nextFactHandle()
+
+	c.insert(h, n, v)
+
+	return h
+}
+
+func (c *structTypeContainer) insert(h FactHandle, key string, v any) {
+	defer c.listeners.factChanging(FactInserted, WorkingMemoryEvent{Type: key, Handle: h, Fact: v})()
+
+	c.handles[h] = &fact{key: key, v: v}
+	c.facts[key] = append(c.facts[key], h)
+	c.changed(key, h)
+}
+
+// Update replaces the fact behind the handle with passed value. It returns false if there is no such fact or if
+// passed value is of another type than the fact.
+func (c *structTypeContainer) Update(h FactHandle, v any) bool {
+	f, exists := c.handles[h]
+	if !exists {
+		return false
 	}
 
-	t := reflect.TypeOf(v)
+	n, v := structValue(v)
+	if n != f.key {
+		return false
+	}
 
-	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
-		t = t.Elem()
-	} else if t.Kind() != reflect.Struct {
-		panic("v must be a struct or a pointer to a struct")
+	defer c.listeners.factChanging(FactUpdated, WorkingMemoryEvent{Type: n, Handle: h, Fact: v, Old: f.v})()
+
+	f.v = v
+	c.changed(n, h)
+
+	return true
+}
+
+// Retract removes the fact behind the handle and returns false if there is no such fact.
+func (c *structTypeContainer) Retract(h FactHandle) bool {
+	f, exists := c.handles[h]
+	if !exists {
+		return false
 	}
 
-	n := t.Name()
+	defer c.listeners.factChanging(FactRetracted, WorkingMemoryEvent{Type: f.key, Handle: h, Fact: f.v})()
 
-	if t.PkgPath() != "" {
-		n = t.PkgPath() + "." + n
+	delete(c.handles, h)
+	delete(c.events, h)
+
+	c.facts[f.key] = slices.DeleteFunc(c.facts[f.key], func(e FactHandle) bool { return e == h })
+	if len(c.facts[f.key]) == 0 {
+		delete(c.facts, f.key)
 	}
 
-	return c.vals[n]
+	c.changed(f.key, h)
+
+	return true
 }
 
-// HasNot just checks that if passed value exists in the container and does not fill passed argument, so a struct or a
-// pointer to struct may be passed.
-func (c *structTypeContainer) HasNot(v any) bool {
+// InsertEvent inserts passed value as an event that happened at the time. Events are facts, so they can be updated
+// and retracted as facts, and they are iterated by Facts too.
+func (c *structTypeContainer) InsertEvent(v any, t EventTime) FactHandle {
+	n, v := structValue(v)
+	h := nextFactHandle()
+
+	c.insertEvent(h, n, v, t)
+
+	return h
+}
+
+func (c *structTypeContainer) insertEvent(h FactHandle, key string, v any, t EventTime) {
+	c.events[h] = t
+	c.insert(h, key, v)
+}
+
+// EventTime returns the time of the event behind the handle and false if there is no such event.
+func (c *structTypeContainer) EventTime(h FactHandle) (EventTime, bool) {
+	t, ok := c.events[h]
+
+	return t, ok
+}
+
+// Fact returns a pointer to the fact behind the handle or nil if there is no such fact.
+func (c *structTypeContainer) Fact(h FactHandle) any {
+	if f, exists := c.handles[h]; exists {
+		return f.v
+	}
+
+	return nil
+}
+
+// Facts iterates over all inserted facts of the same type as passed value in order of insertion. Values are pointers
+// to facts. It's safe to insert and retract facts while iterating, facts retracted meanwhile are skipped and facts
+// inserted meanwhile are not visited.
+func (c *structTypeContainer) Facts(v any) iter.Seq2[FactHandle, any] {
+	return c.factsKey(structKey(v))
+}
+
+func (c *structTypeContainer) factsKey(n string) iter.Seq2[FactHandle, any] {
+	return func(yield func(FactHandle, any) bool) {
+		for _, h := range slices.Clone(c.facts[n]) {
+			f, exists := c.handles[h]
+			if !exists {
+				continue
+			}
+
+			if !yield(h, f.v) {
+				return
+			}
+		}
+	}
+}
+
+func (c *structTypeContainer) getKey(key string) any {
+	return c.vals[key]
+}
+
+func (c *structTypeContainer) handleKey(key string) any {
+	return c.vals[key]
+}
+
+func (c *structTypeContainer) setKey(key string, v any) {
+	defer c.listeners.factChanging(FactSet, WorkingMemoryEvent{Type: key, Fact: v, Old: c.vals[key]})()
+
+	c.vals[key] = v
+	c.touch(key)
+}
+
+func (c *structTypeContainer) deleteKey(key string) {
+	if v, exists := c.vals[key]; exists {
+		defer c.listeners.factChanging(FactDeleted, WorkingMemoryEvent{Type: key, Fact: v})()
+
+		delete(c.vals, key)
+		c.touch(key)
+	}
+}
+
+// clear deletes all values and facts keeping versions and observers, so changes are noticed.
+func (c *structTypeContainer) clear() {
+	var after []func()
+
+	for key, v := range c.vals {
+		after = append(after, c.listeners.factChanging(FactDeleted, WorkingMemoryEvent{Type: key, Fact: v}))
+		c.touch(key)
+	}
+
+	for key, hh := range c.facts {
+		for _, h := range hh {
+			e := WorkingMemoryEvent{Type: key, Handle: h, Fact: c.handles[h].v}
+			after = append(after, c.listeners.factChanging(FactRetracted, e))
+		}
+
+		c.changed(key, 0)
+	}
+
+	defer func() {
+		for _, fn := range after {
+			fn()
+		}
+	}()
+
+	clear(c.vals)
+	clear(c.facts)
+	clear(c.handles)
+	clear(c.events)
+}
+
+// eventsKey returns events of the type key ordered by their start.
+func (c *structTypeContainer) eventsKey(key string) []event {
+	var events []event
+
+	for _, h := range c.facts[key] {
+		if t, ok := c.events[h]; ok {
+			events = append(events, event{h: h, v: c.handles[h].v, t: t})
+		}
+	}
+
+	sortEvents(events)
+
+	return events
+}
+
+// expire retracts events of the type key which ended before passed time.
+func (c *structTypeContainer) expire(key string, before time.Time) {
+	for _, h := range slices.Clone(c.facts[key]) {
+		if t, ok := c.events[h]; ok && t.End.Before(before) {
+			c.Retract(h)
+		}
+	}
+}
+
+// factKey returns the type key of the fact behind the handle.
+func (c *structTypeContainer) factKey(h FactHandle) (string, bool) {
+	if f, exists := c.handles[h]; exists {
+		return f.key, true
+	}
+
+	return "", false
+}
+
+// observe registers a function called whenever a fact is inserted, updated or retracted, or whenever all facts of
+// the type may be changed, then the handle is zero.
+func (c *structTypeContainer) observe(fn func(key string, h FactHandle)) {
+	c.observers = append(c.observers, fn)
+}
+
+// changed marks the fact or all facts of the type key if the handle is zero as changed.
+func (c *structTypeContainer) changed(key string, h FactHandle) {
+	c.touch(key)
+
+	for _, fn := range c.observers {
+		fn(key, h)
+	}
+}
+
+// touch marks values and facts of the type key as changed.
+func (c *structTypeContainer) touch(key string) {
+	c.writes++
+	c.versions[key] = c.writes
+}
+
+// structKey returns the key of the type of passed struct or pointer to struct.
+func structKey(v any) string {
 	if v == nil {
 		panic("v cannot be nil")
 	}
@@ -106,20 +340,12 @@ func (c *structTypeContainer) HasNot(v any) bool {
 		panic("v must be a struct or a pointer to a struct")
 	}
 
-	n := t.Name()
-
-	if t.PkgPath() != "" {
-		n = t.PkgPath() + "." + n
-	}
-
-	_, exists := c.vals[n]
-
-	return !exists
+	return typeKey(t)
 }
 
-// Delete deletes value from container, so passed value must be a struct or a pinter to struct and concrete value
-// doesn't matter.
-func (c *structTypeContainer) Delete(v any) {
+// structValue returns the key of the type of passed struct or pointer to struct and a pointer to the value, so a
+// struct is copied to a new pointer.
+func structValue(v any) (string, any) {
 	if v == nil {
 		panic("v cannot be nil")
 	}
@@ -128,17 +354,23 @@ func (c *structTypeContainer) Delete(v any) {
 
 	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
 		t = t.Elem()
-	} else if t.Kind() != reflect.Struct {
+	} else if t.Kind() == reflect.Struct {
+		ptr := reflect.New(t)
+		ptr.Elem().Set(reflect.ValueOf(v))
+		v = ptr.Interface()
+	} else {
 		panic("v must be a struct or a pointer to a struct")
 	}
 
+	return typeKey(t), v
+}
+
+func typeKey(t reflect.Type) string {
 	n := t.Name()
 
 	if t.PkgPath() != "" {
 		n = t.PkgPath() + "." + n
 	}
 
-	if _, exists := c.vals[n]; exists {
-		delete(c.vals, n)
-	}
+	return n
 }
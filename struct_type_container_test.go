@@ -162,3 +162,82 @@ func TestStructTypeContainer_Handle(t *testing.T) {
 		}
 	}
 }
+
+func TestStructTypeContainer_Insert(t *testing.T) {
+	c := newStructTypeContainer()
+
+	h1 := c.Insert(A{val: "first"})
+	h2 := c.Insert(&A{val: "second"})
+	c.Insert(B{val: "other"})
+
+	if h1 == h2 {
+		t.Fatal("handles must differ")
+	}
+
+	var vals []string
+	for h, v := range c.Facts(A{}) {
+		a, ok := v.(*A)
+		if !ok {
+			t.Fatalf("unexpected fact %T of handle %d", v, h)
+		}
+
+		vals = append(vals, a.val)
+	}
+
+	if len(vals) != 2 || vals[0] != "first" || vals[1] != "second" {
+		t.Fatalf("unexpected facts: %v", vals)
+	}
+
+	if !c.HasNot(A{}) {
+		t.Fatal("inserted facts must not be visible as single values")
+	}
+}
+
+func TestStructTypeContainer_UpdateRetract(t *testing.T) {
+	c := newStructTypeContainer()
+
+	h := c.Insert(A{val: "a"})
+
+	if c.Update(h, B{val: "b"}) {
+		t.Fatal("fact type must not change")
+	}
+
+	if !c.Update(h, A{val: "updated"}) {
+		t.Fatal("fact is not updated")
+	}
+
+	if a, ok := c.Fact(h).(*A); !ok || a.val != "updated" {
+		t.Fatal("unexpected fact")
+	}
+
+	if !c.Retract(h) {
+		t.Fatal("fact is not retracted")
+	}
+
+	if c.Retract(h) || c.Fact(h) != nil {
+		t.Fatal("fact is still there")
+	}
+
+	for range c.Facts(A{}) {
+		t.Fatal("no facts expected")
+	}
+}
+
+func TestStructTypeContainer_Facts_RetractWhileIterating(t *testing.T) {
+	c := newStructTypeContainer()
+
+	c.Insert(A{val: "1"})
+	h := c.Insert(A{val: "2"})
+	c.Insert(A{val: "3"})
+
+	var n int
+	for range c.Facts(A{}) {
+		c.Retract(h)
+		c.Insert(A{val: "4"})
+		n++
+	}
+
+	if n != 2 {
+		t.Fatalf("unexpected number of visited facts: %d", n)
+	}
+}
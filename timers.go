@@ -0,0 +1,100 @@
+package krools
+
+import (
+	"time"
+)
+
+// ruleTimer delays execution of a rule after its condition is satisfied or schedules it by a cron expression.
+type ruleTimer struct {
+	delay time.Duration
+	cron  *cronSchedule
+}
+
+// Timer makes the rule to be executed once the delay elapsed on the session clock since its condition was
+// satisfied, if the condition is still satisfied then. The rule is executed once until its condition is not
+// satisfied again. Rules are executed when the session fires, FireUntilHalt wakes up by itself for that.
+func (r *RuleHandle) Timer(delay time.Duration) *RuleHandle {
+	r.timer = &ruleTimer{delay: delay}
+	ruleRevision.Add(1)
+
+	return r
+}
+
+// Cron makes the rule to be executed on the schedule of the cron expression while its condition is satisfied. The
+// expression has five fields: minute, hour, day of month, month and day of week, it may also be a descriptor like
+// "@hourly" or "@every 10m". It panics if the expression is invalid.
+func (r *RuleHandle) Cron(expr string) *RuleHandle {
+	s, err := parseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	r.timer = &ruleTimer{cron: s}
+	ruleRevision.Add(1)
+
+	return r
+}
+
+func (t *ruleTimer) next(now time.Time) time.Time {
+	if t.cron != nil {
+		return t.cron.next(now)
+	}
+
+	return now.Add(t.delay)
+}
+
+type timerState struct {
+	due   time.Time
+	fired bool
+}
+
+// timers keeps timers of rules of a session armed when their conditions were satisfied.
+type timers struct {
+	armed map[*RuleHandle]*timerState
+}
+
+func newTimers() *timers {
+	return &timers{armed: make(map[*RuleHandle]*timerState)}
+}
+
+// due arms the timer of the rule if it's not armed yet and reports if the rule must be executed now.
+func (t *timers) due(rule *RuleHandle, now time.Time) bool {
+	state, ok := t.armed[rule]
+	if !ok {
+		state = &timerState{due: rule.timer.next(now)}
+		t.armed[rule] = state
+	}
+
+	return !state.fired && !state.due.IsZero() && !now.Before(state.due)
+}
+
+// fired moves the timer of an executed rule to the next time of its schedule or stops a delay timer.
+func (t *timers) fired(rule *RuleHandle, now time.Time) {
+	state, ok := t.armed[rule]
+	if !ok {
+		return
+	}
+
+	if rule.timer.cron != nil {
+		state.due = rule.timer.next(now)
+	} else {
+		state.fired = true
+	}
+}
+
+func (t *timers) disarm(rule *RuleHandle) {
+	delete(t.armed, rule)
+}
+
+// next returns the earliest time a timer is due at, it's zero if no timer is waiting.
+func (t *timers) next() time.Time {
+	var next time.Time
+
+	for _, state := range t.armed {
+		if !state.fired && !state.due.IsZero() && (next.IsZero() || state.due.Before(next)) {
+			next = state.due
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,204 @@
+package krools_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Pending struct {
+	id int
+}
+
+type Escalated struct {
+	n int
+}
+
+func escalationBase(attribute func(r *krools.RuleHandle) *krools.RuleHandle) *krools.KnowledgeBase {
+	return krools.NewKnowledgeBase("escalation base").
+		Add(attribute(krools.NewInlineRule("escalate pending", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Pending](ctx), nil
+		}, func(ctx krools.Context) error {
+			e, _ := krools.Get[Escalated](ctx)
+			e.n++
+			krools.Set(ctx, e)
+			return nil
+		})))
+}
+
+func escalations(s *krools.Session) int {
+	e, _ := krools.Get[Escalated](s)
+	return e.n
+}
+
+func TestTimer(t *testing.T) {
+	clock := krools.NewPseudoClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	s := escalationBase(func(r *krools.RuleHandle) *krools.RuleHandle {
+		return r.Timer(30 * time.Minute)
+	}).NewSession().SetClock(clock)
+
+	krools.Set(s, Pending{id: 1})
+
+	fire := func(expected int) {
+		t.Helper()
+
+		if err := s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := escalations(s); n != expected {
+			t.Fatalf("expected %d escalations, got %d", expected, n)
+		}
+	}
+
+	fire(0)
+
+	clock.Advance(29 * time.Minute)
+	fire(0)
+
+	clock.Advance(2 * time.Minute)
+	fire(1)
+
+	clock.Advance(time.Hour)
+	fire(1)
+
+	krools.Delete[Pending](s)
+	fire(1)
+
+	krools.Set(s, Pending{id: 2})
+	fire(1)
+
+	clock.Advance(30 * time.Minute)
+	fire(2)
+}
+
+func TestCron(t *testing.T) {
+	clock := krools.NewPseudoClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	s := escalationBase(func(r *krools.RuleHandle) *krools.RuleHandle {
+		return r.Cron("*/15 * * * *")
+	}).NewSession().SetClock(clock)
+
+	krools.Set(s, Pending{id: 1})
+
+	for range 4 {
+		clock.Advance(5 * time.Minute)
+
+		if err := s.FireAllRules(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := escalations(s); n != 1 {
+		t.Fatalf("expected 1 escalation, got %d", n)
+	}
+
+	clock.Advance(time.Hour)
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := escalations(s); n != 2 {
+		t.Fatalf("expected 2 escalations, got %d", n)
+	}
+}
+
+func TestCron_Invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+
+	krools.NewInlineRule("invalid", nil, nil).Cron("61 * * * *")
+}
+
+func TestTimer_FireUntilHalt(t *testing.T) {
+	clock := krools.NewPseudoClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	k := krools.NewKnowledgeBase("escalation base").
+		Add(krools.NewInlineRule("escalate and halt", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Pending](ctx), nil
+		}, func(ctx krools.Context) error {
+			krools.Set(ctx, Escalated{n: 1})
+			ctx.Halt()
+			return nil
+		}).Timer(time.Minute))
+
+	s := k.NewSession().SetClock(clock)
+	krools.Set(s, Pending{id: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.FireUntilHalt(ctx) }()
+
+	start := clock.Now()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if escalations(s) != 1 {
+				t.Fatal("halted without escalation")
+			}
+
+			if clock.Now().Sub(start) < time.Minute {
+				t.Fatal("escalated before the timer")
+			}
+
+			return
+		case <-time.After(time.Millisecond):
+			clock.Advance(time.Second)
+		}
+	}
+}
+
+type countingClock struct {
+	*krools.PseudoClock
+
+	afters atomic.Int32
+}
+
+func (c *countingClock) After(d time.Duration) <-chan time.Time {
+	c.afters.Add(1)
+
+	return c.PseudoClock.After(d)
+}
+
+func TestTimer_FireUntilHalt_Waiters(t *testing.T) {
+	clock := &countingClock{PseudoClock: krools.NewPseudoClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+
+	s := escalationBase(func(r *krools.RuleHandle) *krools.RuleHandle {
+		return r.Timer(time.Hour)
+	}).NewSession().SetClock(clock)
+
+	krools.Set(s, Pending{id: 1})
+
+	done := make(chan error, 1)
+	go func() { done <- s.FireUntilHalt(context.Background()) }()
+
+	for i := range 50 {
+		krools.Set(s, Counter{n: i})
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Halt()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := clock.afters.Load(); n != 1 {
+		t.Fatalf("expected the pending timer to be reused, clock is waited %d times", n)
+	}
+}
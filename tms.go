@@ -0,0 +1,128 @@
+package krools
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// support is what a satisfied condition of a rule depended on.
+type support struct {
+	reads    map[string]struct{}
+	volatile bool
+}
+
+// justification is a reason for a logically set value to stay in working memory: the rule whose condition was
+// satisfied and versions of types its condition read at that moment.
+type justification struct {
+	rule     *RuleHandle
+	versions map[string]uint64
+	volatile bool
+}
+
+func newJustification(key string, rule *RuleHandle, s support, c *structTypeContainer) *justification {
+	j := &justification{
+		rule:     rule,
+		versions: make(map[string]uint64, len(s.reads)),
+		volatile: s.volatile,
+	}
+
+	for read := range s.reads {
+		if read != key {
+			j.versions[read] = c.versions[read]
+		}
+	}
+
+	return j
+}
+
+func (j *justification) unchanged(c *structTypeContainer) bool {
+	if j.volatile {
+		return false
+	}
+
+	for key, version := range j.versions {
+		if c.versions[key] != version {
+			return false
+		}
+	}
+
+	return true
+}
+
+// truthMaintenance keeps justifications of logically set values by their type keys.
+type truthMaintenance struct {
+	justified map[string][]*justification
+}
+
+func newTruthMaintenance() *truthMaintenance {
+	return &truthMaintenance{justified: make(map[string][]*justification)}
+}
+
+// justify adds the justification of the value, a rule justifies a value once.
+func (t *truthMaintenance) justify(key string, j *justification) {
+	t.justified[key] = append(slices.DeleteFunc(t.justified[key], func(e *justification) bool {
+		return e.rule == j.rule
+	}), j)
+}
+
+// unjustify makes the value stated, so it's not deleted automatically anymore.
+func (t *truthMaintenance) unjustify(key string) {
+	delete(t.justified, key)
+}
+
+func (t *truthMaintenance) reset() {
+	clear(t.justified)
+}
+
+// maintainTruth deletes logically set values that lost all their justifications. A justification is checked again
+// only if facts its condition read are changed, the condition is checked as if the justified value is not set and
+// without notifying listeners or counting it as an evaluation. Deleted values may break other justifications, so
+// it's repeated until nothing is deleted.
+func (s *Session) maintainTruth(ctx *fireContext) error {
+	for {
+		var deleted bool
+
+		for _, key := range slices.Sorted(maps.Keys(s.tms.justified)) {
+			v, exists := ctx.vals[key]
+			if !exists {
+				s.tms.unjustify(key)
+				continue
+			}
+
+			var held []*justification
+
+			for _, j := range s.tms.justified[key] {
+				if j.rule.condition == nil || j.unchanged(ctx.structTypeContainer) {
+					held = append(held, j)
+					continue
+				}
+
+				delete(ctx.vals, key)
+				satisfied, reads, volatile, err := s.check(ctx, j.rule)
+				ctx.vals[key] = v
+
+				if err != nil {
+					return fmt.Errorf("maintain justification of logically set %s by rule '%s': %w", key, j.rule.name, err)
+				}
+
+				if satisfied {
+					held = append(held, newJustification(key, j.rule, support{reads: reads, volatile: volatile}, ctx.structTypeContainer))
+				}
+			}
+
+			if len(held) > 0 {
+				s.tms.justified[key] = held
+				continue
+			}
+
+			s.tms.unjustify(key)
+			ctx.deleteKey(key)
+			deleted = true
+		}
+
+		if !deleted {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package krools_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type Banner struct{}
+
+func truthMaintenanceBase() *krools.KnowledgeBase {
+	return krools.NewKnowledgeBase("truth maintenance base").
+		Add(krools.NewInlineRule("discount for gold customers", func(ctx krools.Context) (bool, error) {
+			c, ok := krools.Get[Customer](ctx)
+			return ok && c.Tier == "gold" && !krools.Has[Discount](ctx), nil
+		}, func(ctx krools.Context) error {
+			ctx.SetLogical(Discount{percent: 5})
+			return nil
+		})).
+		Add(krools.NewInlineRule("banner for discounts", func(ctx krools.Context) (bool, error) {
+			return krools.Has[Discount](ctx) && !krools.Has[Banner](ctx), nil
+		}, func(ctx krools.Context) error {
+			ctx.SetLogical(Banner{})
+			return nil
+		}))
+}
+
+func TestSetLogical(t *testing.T) {
+	s := truthMaintenanceBase().NewSession()
+	krools.Set(s, Customer{ID: 1, Tier: "gold"})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !krools.Has[Discount](s) || !krools.Has[Banner](s) {
+		t.Fatal("logical values are not set")
+	}
+
+	krools.Set(s, Customer{ID: 1, Tier: "silver"})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if krools.Has[Discount](s) || krools.Has[Banner](s) {
+		t.Fatal("logical values are not deleted")
+	}
+}
+
+func TestSetLogical_Stated(t *testing.T) {
+	s := truthMaintenanceBase().NewSession()
+	krools.Set(s, Customer{ID: 1, Tier: "gold"})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	krools.Set(s, Discount{percent: 20})
+	krools.Delete[Customer](s)
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, ok := krools.Get[Discount](s); !ok || d.percent != 20 {
+		t.Fatal("stated value is deleted")
+	}
+
+	if !krools.Has[Banner](s) {
+		t.Fatal("banner justified by stated discount is deleted")
+	}
+}
+
+func TestSetLogical_SilentJustifications(t *testing.T) {
+	s := truthMaintenanceBase().NewSession()
+	krools.Set(s, Customer{ID: 1, Tier: "gold"})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &agendaRecorder{}
+	s.AddAgendaEventListener(recorder)
+	krools.Set(s, Customer{ID: 1, Tier: "silver"})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"condition discount for gold customers false", "condition banner for discounts false"}
+	if !slices.Equal(recorder.events, want) {
+		t.Fatalf("expected justifications to be checked silently, got %v", recorder.events)
+	}
+}
@@ -0,0 +1,229 @@
+package krools
+
+import (
+	"fmt"
+)
+
+// TraceReason is what happened to a rule at a step of an execution trace.
+type TraceReason int
+
+const (
+	// ReasonNotEvaluated means the rule was never looked at, because it doesn't exist, its unit was not reached or
+	// firing was halted before.
+	ReasonNotEvaluated TraceReason = iota
+	// ReasonFired means the action of the rule was executed.
+	ReasonFired
+	// ReasonMatched means the condition of the rule was satisfied, but the rule was not executed yet.
+	ReasonMatched
+	// ReasonConditionFalse means the condition of the rule was not satisfied.
+	ReasonConditionFalse
+	// ReasonConditionError means the condition of the rule returned an error.
+	ReasonConditionError
+	// ReasonFiltered means the rule was not satisfied by a filter passed to fire.
+	ReasonFiltered
+	// ReasonRetracted means the rule was retracted by another rule.
+	ReasonRetracted
+	// ReasonUnitDeactivated means the unit of the rule was deactivated.
+	ReasonUnitDeactivated
+	// ReasonNoLoop means the rule was not evaluated again because of NoLoop.
+	ReasonNoLoop
+	// ReasonTimerPending means the condition of the rule was satisfied, but its timer was not due yet.
+	ReasonTimerPending
+)
+
+func (r TraceReason) String() string {
+	switch r {
+	case ReasonNotEvaluated:
+		return "not evaluated"
+	case ReasonFired:
+		return "fired"
+	case ReasonMatched:
+		return "matched"
+	case ReasonConditionFalse:
+		return "condition false"
+	case ReasonConditionError:
+		return "condition error"
+	case ReasonFiltered:
+		return "filtered"
+	case ReasonRetracted:
+		return "retracted"
+	case ReasonUnitDeactivated:
+		return "unit deactivated"
+	case ReasonNoLoop:
+		return "no-loop"
+	case ReasonTimerPending:
+		return "timer pending"
+	default:
+		return "unknown"
+	}
+}
+
+// FactChange is a change of working memory made by an action.
+type FactChange struct {
+	Operation FactOperation
+	Type      string
+	Handle    FactHandle
+}
+
+// TraceStep is what happened to a rule in a cycle. RetractedBy is the name of the rule that retracted the rule or
+// deactivated its unit, it's empty if the unit was deactivated before firing. Err is the error of the condition or
+// the action. Changes are changes of working memory made by the action of a fired rule.
+type TraceStep struct {
+	Rule        string
+	Unit        string
+	Reason      TraceReason
+	RetractedBy string
+	Err         error
+	Changes     []FactChange
+}
+
+// TraceCycle is a cycle of looking for applicable rules of the unit and executing them.
+type TraceCycle struct {
+	Unit  string
+	Steps []TraceStep
+}
+
+// ExecutionTrace is the sequence of cycles of a fire.
+type ExecutionTrace struct {
+	Cycles []TraceCycle
+}
+
+// Explanation tells why a rule did or did not fire in the last fire.
+type Explanation struct {
+	Rule        string
+	Reason      TraceReason
+	RetractedBy string
+	Err         error
+	// Executions is the number of times the rule fired.
+	Executions int
+}
+
+func (e Explanation) String() string {
+	switch {
+	case e.Reason == ReasonFired:
+		return fmt.Sprintf("rule '%s' fired %d time(s)", e.Rule, e.Executions)
+	case e.Reason == ReasonRetracted:
+		return fmt.Sprintf("rule '%s' was retracted by rule '%s'", e.Rule, e.RetractedBy)
+	case e.Reason == ReasonUnitDeactivated && e.RetractedBy != "":
+		return fmt.Sprintf("rule '%s' is in a unit deactivated by rule '%s'", e.Rule, e.RetractedBy)
+	case e.Reason == ReasonUnitDeactivated:
+		return fmt.Sprintf("rule '%s' is in a deactivated unit", e.Rule)
+	case e.Err != nil:
+		return fmt.Sprintf("rule '%s' did not fire: %s: %s", e.Rule, e.Reason, e.Err)
+	default:
+		return fmt.Sprintf("rule '%s' did not fire: %s", e.Rule, e.Reason)
+	}
+}
+
+// Explain tells why the rule did or did not fire in the trace. If the rule fired it's explained as fired, otherwise
+// the last thing happened to it explains it, but skipping it because of NoLoop doesn't override a condition that
+// was not satisfied or failed before.
+func (t *ExecutionTrace) Explain(rule string) Explanation {
+	e := Explanation{Rule: rule}
+
+	if t == nil {
+		return e
+	}
+
+	for _, cycle := range t.Cycles {
+		for _, step := range cycle.Steps {
+			if step.Rule != rule {
+				continue
+			}
+
+			if step.Reason == ReasonFired {
+				e.Executions++
+			}
+
+			if step.Reason == ReasonNoLoop && (e.Reason == ReasonConditionFalse || e.Reason == ReasonConditionError) {
+				continue
+			}
+
+			if e.Executions == 0 || step.Reason == ReasonFired {
+				e.Reason = step.Reason
+				e.RetractedBy = step.RetractedBy
+				e.Err = step.Err
+			}
+		}
+	}
+
+	return e
+}
+
+func (t *ExecutionTrace) cycle(unit string) {
+	if t != nil {
+		t.Cycles = append(t.Cycles, TraceCycle{Unit: unit})
+	}
+}
+
+func (t *ExecutionTrace) step(rule *RuleHandle, reason TraceReason, err error) {
+	if t == nil {
+		return
+	}
+
+	if len(t.Cycles) == 0 {
+		t.cycle(rule.unit)
+	}
+
+	c := &t.Cycles[len(t.Cycles)-1]
+	c.Steps = append(c.Steps, TraceStep{Rule: rule.name, Unit: rule.unit, Reason: reason, Err: err})
+}
+
+func (t *ExecutionTrace) retracted(rule *RuleHandle, cause retraction) {
+	if t == nil {
+		return
+	}
+
+	reason := ReasonRetracted
+	if cause.unit != "" {
+		reason = ReasonUnitDeactivated
+	}
+
+	t.step(rule, reason, nil)
+	t.last().RetractedBy = cause.by
+}
+
+// change adds the change to the step of the rule being executed.
+func (t *ExecutionTrace) change(op FactOperation, e WorkingMemoryEvent) {
+	if t == nil || e.Rule == nil {
+		return
+	}
+
+	if step := t.last(); step != nil && step.Reason == ReasonFired && step.Rule == e.Rule.name {
+		step.Changes = append(step.Changes, FactChange{Operation: op, Type: e.Type, Handle: e.Handle})
+	}
+}
+
+func (t *ExecutionTrace) last() *TraceStep {
+	if len(t.Cycles) == 0 {
+		return nil
+	}
+
+	c := &t.Cycles[len(t.Cycles)-1]
+	if len(c.Steps) == 0 {
+		return nil
+	}
+
+	return &c.Steps[len(c.Steps)-1]
+}
+
+// SetExecutionTrace turns on or off recording of execution traces. When it's on every fire records what happened to
+// rules, it's available with ExecutionTrace and Explain until the next fire.
+func (s *Session) SetExecutionTrace(enabled bool) *Session {
+	s.tracing = enabled
+
+	return s
+}
+
+// ExecutionTrace returns the trace of the last fire or nil if tracing is off.
+func (s *Session) ExecutionTrace() *ExecutionTrace {
+	var t *ExecutionTrace
+	s.read(func() { t = s.listeners.trace })
+
+	return t
+}
+
+// Explain tells why the rule did or did not fire in the last fire. Tracing must be turned on with SetExecutionTrace.
+func (s *Session) Explain(rule string) Explanation {
+	return s.ExecutionTrace().Explain(rule)
+}
@@ -0,0 +1,128 @@
+package krools_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+const discountType = "github.com/krocos/krools/v2_test.Discount"
+
+func TestExplain(t *testing.T) {
+	has := func(ctx krools.Context) (bool, error) { return krools.Has[Trigger](ctx), nil }
+
+	k := krools.NewKnowledgeBase("explain base").
+		Add(krools.NewInlineRule("discount", has, func(ctx krools.Context) error {
+			krools.Set(ctx, Discount{percent: 10})
+			return nil
+		}).Salience(10).Deactivate("discount", "no discount for vip").DeactivateUnits("vip")).
+		Add(krools.NewInlineRule("no discount for vip", has, noop)).
+		Add(krools.NewInlineRule("large order", func(ctx krools.Context) (bool, error) {
+			return krools.Has[LargeOrders](ctx), nil
+		}, noop)).
+		Add(krools.NewInlineRule("skipped by filter", has, noop).Deactivate()).
+		Add(krools.NewInlineRule("count", func(ctx krools.Context) (bool, error) {
+			c, _ := krools.Get[Counter](ctx)
+			return c.n < 3, nil
+		}, func(ctx krools.Context) error {
+			c, _ := krools.Get[Counter](ctx)
+			krools.Set(ctx, Counter{n: c.n + 1})
+			return nil
+		}).NoLoop()).
+		AddUnit("vip", krools.NewInlineRule("vip", has, noop)).
+		AddUnit("deactivated", krools.NewInlineRule("deactivated", has, noop))
+
+	s := k.NewSession().SetExecutionTrace(true).SetDeactivatedUnits("deactivated")
+	krools.Set(s, Trigger{})
+
+	if s.Explain("discount").Reason != krools.ReasonNotEvaluated {
+		t.Fatal("explained before fire")
+	}
+
+	if err := s.FireAllRules(context.Background(), krools.RuleNameMustNotContainsAny("filter")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		rule        string
+		reason      krools.TraceReason
+		retractedBy string
+	}{
+		{"discount", krools.ReasonFired, ""},
+		{"no discount for vip", krools.ReasonRetracted, "discount"},
+		{"large order", krools.ReasonConditionFalse, ""},
+		{"skipped by filter", krools.ReasonFiltered, ""},
+		{"count", krools.ReasonFired, ""},
+		{"vip", krools.ReasonUnitDeactivated, "discount"},
+		{"deactivated", krools.ReasonUnitDeactivated, ""},
+		{"unknown", krools.ReasonNotEvaluated, ""},
+	}
+
+	for _, c := range cases {
+		e := s.Explain(c.rule)
+		if e.Reason != c.reason || e.RetractedBy != c.retractedBy {
+			t.Errorf("%s: unexpected explanation: %s", c.rule, e)
+		}
+	}
+
+	if e := s.Explain("no discount for vip"); e.String() != "rule 'no discount for vip' was retracted by rule 'discount'" {
+		t.Errorf("unexpected explanation: %s", e)
+	}
+
+	var (
+		changes []krools.FactChange
+		noLoop  bool
+	)
+
+	for _, cycle := range s.ExecutionTrace().Cycles {
+		for _, step := range cycle.Steps {
+			if step.Rule == "discount" && step.Reason == krools.ReasonFired {
+				changes = step.Changes
+			}
+
+			noLoop = noLoop || step.Rule == "count" && step.Reason == krools.ReasonNoLoop
+		}
+	}
+
+	if len(changes) != 1 || changes[0].Operation != krools.FactSet || changes[0].Type != discountType {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	if !noLoop {
+		t.Error("no-loop is not traced")
+	}
+}
+
+func TestExplain_NoLoopConditionFalse(t *testing.T) {
+	k := krools.NewKnowledgeBase("explain base").
+		Add(krools.NewInlineRule("never", func(ctx krools.Context) (bool, error) {
+			return krools.Has[LargeOrders](ctx), nil
+		}, noop).NoLoop()).
+		Add(krools.NewInlineRule("once", nil, func(ctx krools.Context) error {
+			krools.Set(ctx, Trigger{})
+			return nil
+		}).Deactivate())
+
+	s := k.NewSession().SetExecutionTrace(true)
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var noLoop bool
+
+	for _, cycle := range s.ExecutionTrace().Cycles {
+		for _, step := range cycle.Steps {
+			noLoop = noLoop || step.Rule == "never" && step.Reason == krools.ReasonNoLoop
+		}
+	}
+
+	if !noLoop {
+		t.Fatal("expected rule 'never' to be skipped by no-loop after the condition was false")
+	}
+
+	if e := s.Explain("never"); e.Reason != krools.ReasonConditionFalse {
+		t.Errorf("unexpected explanation: %s", e)
+	}
+}
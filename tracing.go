@@ -0,0 +1,76 @@
+package krools
+
+import (
+	"context"
+)
+
+// Keys of attributes of spans.
+const (
+	AttributeKnowledgeBase = "krools.knowledge_base"
+	AttributeUnit          = "krools.unit"
+	AttributeRule          = "krools.rule"
+	AttributeSalience      = "krools.salience"
+	AttributeCycle         = "krools.cycle"
+)
+
+// Attribute annotates a span. Values are strings and ints.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer starts spans of a tracing system. Sessions start spans named "krools.fire", "krools.unit", "krools.cycle",
+// "krools.condition" and "krools.action" for a fire, every unit fired, every cycle, every evaluated condition and every
+// executed action. The context returned by Start is the one conditions and actions get from Context.Context, so their
+// spans are nested. See the otel sub-module for an
+// OpenTelemetry adapter.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, TraceSpan)
+}
+
+// TraceSpan is a span started by Tracer.
+type TraceSpan interface {
+	// RecordError marks the span as failed with the error.
+	RecordError(err error)
+	End()
+}
+
+// SetTracer sets the tracer of the session, spans are not started if it's nil which is the default.
+func (s *Session) SetTracer(tracer Tracer) *Session {
+	s.tracer = tracer
+
+	return s
+}
+
+// SetTracer sets the tracer of sessions created after.
+func (k *KnowledgeBase) SetTracer(tracer Tracer) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.tracer = tracer
+
+	return k
+}
+
+// startSpan starts the span if the session has a tracer, the span becomes the context of the fire context until the
+// returned function ends it.
+func (s *Session) startSpan(ctx *fireContext, name string, attributes ...Attribute) func(err error) {
+	if s.tracer == nil {
+		return func(error) {}
+	}
+
+	parent := ctx.ctx
+	spanCtx, span := s.tracer.Start(parent, name, attributes...)
+	ctx.ctx = spanCtx
+
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+		}
+
+		span.End()
+		ctx.ctx = parent
+	}
+}
@@ -0,0 +1,80 @@
+package krools_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+type spanKey struct{}
+
+type recordingTracer struct {
+	started []string
+	ended   []string
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	name   string
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes ...krools.Attribute) (context.Context, krools.TraceSpan) {
+	for _, a := range attributes {
+		if a.Key == krools.AttributeRule {
+			name += " " + a.Value.(string)
+		}
+	}
+
+	t.started = append(t.started, name)
+
+	return context.WithValue(ctx, spanKey{}, name), &recordingSpan{tracer: t, name: name}
+}
+
+func (s *recordingSpan) RecordError(error) {}
+
+func (s *recordingSpan) End() {
+	s.tracer.ended = append(s.tracer.ended, s.name)
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+
+	var conditionSpan, span any
+
+	k := krools.NewKnowledgeBase("traced base").
+		Add(krools.NewInlineRule("rule", func(ctx krools.Context) (bool, error) {
+			conditionSpan = ctx.Context().Value(spanKey{})
+			return krools.Has[Trigger](ctx), nil
+		}, func(ctx krools.Context) error {
+			span = ctx.Context().Value(spanKey{})
+			return nil
+		}).Deactivate())
+
+	s := k.NewSession().SetTracer(tracer)
+	krools.Set(s, Trigger{})
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if conditionSpan != "krools.condition rule" {
+		t.Errorf("condition got context of span %v", conditionSpan)
+	}
+
+	if span != "krools.action rule" {
+		t.Errorf("action got context of span %v", span)
+	}
+
+	expected := []string{
+		"krools.fire", "krools.unit", "krools.cycle", "krools.condition rule", "krools.action rule", "krools.cycle",
+	}
+	if !slices.Equal(tracer.started, expected) {
+		t.Errorf("unexpected started spans: %v", tracer.started)
+	}
+
+	if len(tracer.ended) != len(expected) || tracer.ended[len(expected)-1] != "krools.fire" {
+		t.Errorf("unexpected ended spans: %v", tracer.ended)
+	}
+}
@@ -0,0 +1,160 @@
+package krools
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownRule is a reference to a rule which is not in the knowledge base.
+	ErrUnknownRule = errors.New("unknown rule")
+	// ErrUnknownUnit is a reference to a unit which has no rules.
+	ErrUnknownUnit = errors.New("unknown unit")
+	// ErrDuplicateRule is a name of rules of different units, they are retracted and activated together.
+	ErrDuplicateRule = errors.New("duplicate rule")
+	// ErrNoopRule is a rule without a condition, an action and attributes changing the flow, so it does nothing.
+	ErrNoopRule = errors.New("rule does nothing")
+	// ErrUnreachableUnit is a unit which is deactivated and not activated by any rule that may be executed.
+	ErrUnreachableUnit = errors.New("unreachable unit")
+)
+
+// SetDeactivatedUnits deactivates units for sessions created after, rules may activate them with ActivateUnits.
+func (k *KnowledgeBase) SetDeactivatedUnits(units ...string) *KnowledgeBase {
+	k.mustNotBeBuilt()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.deactivatedUnits = uniq(append(units, k.deactivatedUnits...))
+	k.stateless = nil
+
+	return k
+}
+
+// Validate checks that rules refer to existing rules and units, that names of rules are unique across units, that
+// every rule does something and that every unit may be reached. It returns all problems joined, they may be checked
+// with errors.Is against ErrUnknownRule, ErrUnknownUnit, ErrDuplicateRule, ErrNoopRule and ErrUnreachableUnit.
+func (k *KnowledgeBase) Validate() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.validate()
+}
+
+func (k *KnowledgeBase) validate() error {
+	var errs []error
+
+	ruleUnits := make(map[string]string)
+
+	for _, unit := range k.unitsOrder {
+		for _, rule := range k.units[unit] {
+			if other, ok := ruleUnits[rule.name]; ok {
+				errs = append(errs, fmt.Errorf("%w: rule '%s' is in units '%s' and '%s'", ErrDuplicateRule, rule.name, other, unit))
+				continue
+			}
+
+			ruleUnits[rule.name] = unit
+		}
+	}
+
+	knownUnit := func(unit string) bool {
+		_, ok := k.units[unit]
+		return ok || unit == UnitMAIN
+	}
+
+	for _, unit := range k.unitsOrder {
+		for _, rule := range k.units[unit] {
+			for _, name := range rule.retracts {
+				if _, ok := ruleUnits[name]; !ok {
+					errs = append(errs, fmt.Errorf("%w: rule '%s' deactivates rule '%s'", ErrUnknownRule, rule.name, name))
+				}
+			}
+
+			for _, name := range rule.inserts {
+				if _, ok := ruleUnits[name]; !ok {
+					errs = append(errs, fmt.Errorf("%w: rule '%s' activates rule '%s'", ErrUnknownRule, rule.name, name))
+				}
+			}
+
+			for _, refs := range []struct {
+				verb  string
+				units []string
+			}{
+				{"deactivates", rule.deactivateUnits},
+				{"activates", rule.activateUnits},
+				{"focuses", rule.focusUnits},
+			} {
+				for _, u := range refs.units {
+					if !knownUnit(u) {
+						errs = append(errs, fmt.Errorf("%w: rule '%s' %s unit '%s'", ErrUnknownUnit, rule.name, refs.verb, u))
+					}
+				}
+			}
+
+			if rule.noop() {
+				errs = append(errs, fmt.Errorf("%w: rule '%s'", ErrNoopRule, rule.name))
+			}
+		}
+	}
+
+	for _, u := range k.deactivatedUnits {
+		if !knownUnit(u) {
+			errs = append(errs, fmt.Errorf("%w: unit '%s' is deactivated", ErrUnknownUnit, u))
+		}
+	}
+
+	for _, unit := range k.unreachableUnits() {
+		errs = append(errs, fmt.Errorf("%w: unit '%s'", ErrUnreachableUnit, unit))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("validate knowledge base '%s': %w", k.name, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// unreachableUnits returns units deactivated from the start which are not activated by rules of reachable units.
+func (k *KnowledgeBase) unreachableUnits() []string {
+	reachable := make(map[string]struct{})
+
+	var queue []string
+
+	for _, unit := range k.unitsOrder {
+		if !contains(k.deactivatedUnits, unit) {
+			queue = append(queue, unit)
+		}
+	}
+
+	for len(queue) > 0 {
+		unit := queue[0]
+		queue = queue[1:]
+
+		if _, ok := reachable[unit]; ok {
+			continue
+		}
+
+		reachable[unit] = struct{}{}
+
+		for _, rule := range k.units[unit] {
+			queue = append(queue, rule.activateUnits...)
+		}
+	}
+
+	var unreachable []string
+
+	for _, unit := range k.unitsOrder {
+		if _, ok := reachable[unit]; !ok {
+			unreachable = append(unreachable, unit)
+		}
+	}
+
+	return unreachable
+}
+
+// noop reports if the rule does nothing when it's executed.
+func (r *RuleHandle) noop() bool {
+	return r.condition == nil && r.action == nil && r.activationUnit == nil && r.timer == nil &&
+		len(r.retracts) == 0 && len(r.inserts) == 0 &&
+		len(r.activateUnits) == 0 && len(r.deactivateUnits) == 0 && len(r.focusUnits) == 0
+}
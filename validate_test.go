@@ -0,0 +1,82 @@
+package krools_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/krocos/krools/v2"
+)
+
+func TestKnowledgeBase_Validate(t *testing.T) {
+	kb := krools.NewKnowledgeBase("validate").
+		Add(krools.NewInlineRule("start", nil, noop).Deactivate("missing").ActivateUnits("checks")).
+		Add(krools.NewInlineRule("check", nil, noop).Unit("checks").Activate("absent").SetFocus("nowhere")).
+		Add(krools.NewInlineRule("start", nil, noop).Unit("orphan")).
+		Add(krools.NewInlineRule("nothing", nil, nil)).
+		Add(krools.NewInlineRule("late", nil, noop).Unit("late")).
+		SetDeactivatedUnits("checks", "orphan", "late", "ghost")
+
+	err := kb.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, target := range []error{
+		krools.ErrUnknownRule,
+		krools.ErrUnknownUnit,
+		krools.ErrDuplicateRule,
+		krools.ErrNoopRule,
+		krools.ErrUnreachableUnit,
+	} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected %q in %v", target, err)
+		}
+	}
+
+	for _, want := range []string{
+		"rule 'start' deactivates rule 'missing'",
+		"rule 'check' activates rule 'absent'",
+		"rule 'check' focuses unit 'nowhere'",
+		"rule 'start' is in units 'MAIN' and 'orphan'",
+		"rule does nothing: rule 'nothing'",
+		"unit 'ghost' is deactivated",
+		"unreachable unit: unit 'orphan'",
+		"unreachable unit: unit 'late'",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	if strings.Contains(err.Error(), "unit 'checks'") {
+		t.Errorf("unit 'checks' is activated by rule 'start', got %v", err)
+	}
+
+	if _, err := kb.Build(); err == nil {
+		t.Fatal("expected build to fail")
+	}
+}
+
+func TestKnowledgeBase_Build(t *testing.T) {
+	kb, err := krools.NewKnowledgeBase("build").
+		Add(krools.NewInlineRule("start", nil, noop).Deactivate().ActivateUnits("next")).
+		Add(krools.NewInlineRule("next", nil, nil).Unit("next").Deactivate()).
+		SetDeactivatedUnits("next").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := kb.NewSession().SetExecutionTrace(true)
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rule := range []string{"start", "next"} {
+		if e := s.Explain(rule); e.Reason != krools.ReasonFired {
+			t.Errorf("expected rule '%s' to fire, got %s", rule, e)
+		}
+	}
+}
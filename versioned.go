@@ -0,0 +1,219 @@
+package krools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// VersionedKnowledgeBase holds the current version of a knowledge base which may be swapped while sessions are used.
+// Sessions are created of the version current at the moment and keep using it until they are dropped, so sessions
+// running while a new version is swapped in finish on the old one. Versions are numbered from 1.
+type VersionedKnowledgeBase struct {
+	mu      sync.Mutex
+	current atomic.Pointer[knowledgeBaseVersion]
+}
+
+type knowledgeBaseVersion struct {
+	kb      *KnowledgeBase
+	version uint64
+	swapped time.Time
+
+	// dir and state are the directory the version is loaded of by WatchDir and its state before loading.
+	dir   string
+	state string
+}
+
+// modificationSlack is how much earlier than the swap of a version files may look modified and still be taken as
+// modified after it, since file systems keep modification times coarser than the clock.
+const modificationSlack = 2 * time.Second
+
+// NewVersionedKnowledgeBase builds the knowledge base as the first version.
+func NewVersionedKnowledgeBase(kb *KnowledgeBase) (*VersionedKnowledgeBase, error) {
+	v := &VersionedKnowledgeBase{}
+
+	if _, err := v.Swap(kb); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Swap builds the knowledge base and makes it the current version, it returns the number of the version. If the
+// knowledge base is not valid the current version stays.
+func (v *VersionedKnowledgeBase) Swap(kb *KnowledgeBase) (uint64, error) {
+	return v.swap(kb, "", "")
+}
+
+func (v *VersionedKnowledgeBase) swap(kb *KnowledgeBase, dir, state string) (uint64, error) {
+	built, err := kb.Build()
+	if err != nil {
+		return 0, fmt.Errorf("swap knowledge base: %w", err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var version uint64 = 1
+	if current := v.current.Load(); current != nil {
+		version = current.version + 1
+	}
+
+	v.current.Store(&knowledgeBaseVersion{kb: built, version: version, swapped: time.Now(), dir: dir, state: state})
+
+	return version, nil
+}
+
+// KnowledgeBase returns the current version of the knowledge base and its number.
+func (v *VersionedKnowledgeBase) KnowledgeBase() (*KnowledgeBase, uint64) {
+	current := v.current.Load()
+
+	return current.kb, current.version
+}
+
+// Version returns the number of the current version.
+func (v *VersionedKnowledgeBase) Version() uint64 {
+	return v.current.Load().version
+}
+
+// NewSession creates a session of the current version, Session.Version returns its number.
+func (v *VersionedKnowledgeBase) NewSession() *Session {
+	current := v.current.Load()
+
+	s := current.kb.NewSession()
+	s.version = current.version
+
+	return s
+}
+
+// Execute executes facts by the current version like KnowledgeBase.Execute.
+func (v *VersionedKnowledgeBase) Execute(ctx context.Context, facts ...any) (*Result, error) {
+	return v.current.Load().kb.Execute(ctx, facts...)
+}
+
+// Version returns the version of the knowledge base the session is created of by VersionedKnowledgeBase, it's zero
+// for sessions created by KnowledgeBase.NewSession.
+func (s *Session) Version() uint64 {
+	return s.version
+}
+
+// WatchDir polls the directory every interval and swaps in the knowledge base returned by load once names, sizes or
+// modification times of its files changed. The knowledge base is loaded at once if the directory changed since the
+// current version was loaded of it by WatchDir, or, for versions swapped in otherwise, if the directory or its files
+// look modified since the version was swapped in, so changes made before watching are not missed. Errors of load and
+// invalid knowledge bases met while polling are passed to onError if it's not nil, the current version stays then
+// until files are changed again. It blocks until the context is done and returns the error of the context, or the
+// error of the interval, reading the directory or the first load.
+func (v *VersionedKnowledgeBase) WatchDir(
+	ctx context.Context,
+	dir string,
+	interval time.Duration,
+	load func(dir string) (*KnowledgeBase, error),
+	onError func(err error),
+) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch directory '%s': interval %s is not positive", dir, interval)
+	}
+
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	seen, modified, err := dirState(dir)
+	if err != nil {
+		return fmt.Errorf("watch directory '%s': %w", dir, err)
+	}
+
+	current := v.current.Load()
+	changed := seen != current.state
+	if current.dir != dir {
+		changed = modified.After(current.swapped.Add(-modificationSlack))
+	}
+
+	if changed {
+		if seen, err = v.reload(dir, load); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		state, _, err := dirState(dir)
+		if err != nil {
+			onError(fmt.Errorf("watch directory '%s': %w", dir, err))
+			continue
+		}
+
+		if state == seen {
+			continue
+		}
+
+		if seen, err = v.reload(dir, load); err != nil {
+			onError(err)
+		}
+	}
+}
+
+// reload swaps in the knowledge base of the directory and returns the state of the directory it's loaded of.
+func (v *VersionedKnowledgeBase) reload(dir string, load func(dir string) (*KnowledgeBase, error)) (string, error) {
+	state, _, err := dirState(dir)
+	if err != nil {
+		return "", fmt.Errorf("watch directory '%s': %w", dir, err)
+	}
+
+	kb, err := load(dir)
+	if err != nil {
+		return state, fmt.Errorf("load knowledge base of directory '%s': %w", dir, err)
+	}
+
+	if _, err = v.swap(kb, dir, state); err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
+
+// dirState describes names, sizes and modification times of files of the directory, ordered by name. It also returns
+// the latest modification time of the directory and its files, the directory is modified when files are added or
+// removed.
+func dirState(dir string) (string, time.Time, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	modified := info.ModTime()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var state string
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		state += fmt.Sprintf("%s %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+
+	return state, modified, nil
+}
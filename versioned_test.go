@@ -0,0 +1,208 @@
+package krools_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/krocos/krools/v2"
+)
+
+func discountKnowledgeBase(percent int) *krools.KnowledgeBase {
+	return krools.NewKnowledgeBase("versioned").
+		Add(krools.NewInlineRule("discount", nil, func(ctx krools.Context) error {
+			krools.Set(ctx, Discount{percent: percent})
+			return nil
+		}).Deactivate())
+}
+
+func fireDiscount(t *testing.T, s *krools.Session) int {
+	t.Helper()
+
+	if err := s.FireAllRules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := krools.Get[Discount](s)
+
+	return d.percent
+}
+
+func TestVersionedKnowledgeBase_Swap(t *testing.T) {
+	v, err := krools.NewVersionedKnowledgeBase(discountKnowledgeBase(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := v.NewSession()
+	if old.Version() != 1 {
+		t.Fatalf("expected version 1, got %d", old.Version())
+	}
+
+	version, err := v.Swap(discountKnowledgeBase(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != 2 || v.Version() != 2 {
+		t.Fatalf("expected version 2, got %d and %d", version, v.Version())
+	}
+
+	if percent := fireDiscount(t, old); percent != 5 {
+		t.Errorf("expected old session to finish on version 1, got discount %d", percent)
+	}
+
+	s := v.NewSession()
+	if s.Version() != 2 {
+		t.Errorf("expected version 2, got %d", s.Version())
+	}
+
+	if percent := fireDiscount(t, s); percent != 10 {
+		t.Errorf("expected discount 10, got %d", percent)
+	}
+
+	invalid := krools.NewKnowledgeBase("invalid").Add(krools.NewInlineRule("broken", nil, noop).Activate("missing"))
+	if _, err = v.Swap(invalid); !errors.Is(err, krools.ErrUnknownRule) {
+		t.Errorf("expected unknown rule error, got %v", err)
+	}
+
+	if kb, version := v.KnowledgeBase(); version != 2 || !kb.Built() {
+		t.Errorf("expected built version 2 to stay, got %d", version)
+	}
+
+	if s := krools.NewKnowledgeBase("plain").NewSession(); s.Version() != 0 {
+		t.Errorf("expected version 0 of a plain session, got %d", s.Version())
+	}
+}
+
+func TestVersionedKnowledgeBase_WatchDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "percent")
+
+	if err := os.WriteFile(file, []byte("5"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(dir string) (*krools.KnowledgeBase, error) {
+		b, err := os.ReadFile(filepath.Join(dir, "percent"))
+		if err != nil {
+			return nil, err
+		}
+
+		percent, err := strconv.Atoi(string(b))
+		if err != nil {
+			return nil, err
+		}
+
+		return discountKnowledgeBase(percent), nil
+	}
+
+	// Files written long before the version is swapped in are not reloaded when watching starts.
+	for _, path := range []string{file, dir} {
+		if err := os.Chtimes(path, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kb, err := load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := krools.NewVersionedKnowledgeBase(kb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = v.WatchDir(context.Background(), dir, 0, load, nil); err == nil {
+		t.Error("expected error of zero interval")
+	}
+
+	errs := make(chan error, 10)
+
+	watch := func() (cancel func() error) {
+		ctx, stop := context.WithCancel(context.Background())
+		done := make(chan error)
+
+		go func() {
+			done <- v.WatchDir(ctx, dir, time.Millisecond, load, func(err error) { errs <- err })
+		}()
+
+		return func() error {
+			stop()
+			return <-done
+		}
+	}
+
+	waitVersion := func(version uint64) {
+		t.Helper()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for v.Version() != version {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected version %d, got %d", version, v.Version())
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	stop := watch()
+
+	time.Sleep(20 * time.Millisecond)
+
+	if v.Version() != 1 {
+		t.Fatalf("expected version 1 while files are not changed, got %d", v.Version())
+	}
+
+	if err = os.WriteFile(file, []byte("15"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	waitVersion(2)
+
+	if percent := fireDiscount(t, v.NewSession()); percent != 15 {
+		t.Errorf("expected discount 15, got %d", percent)
+	}
+
+	if err = os.WriteFile(file, []byte("bad"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-errs:
+		if !errors.Is(err, strconv.ErrSyntax) {
+			t.Errorf("expected syntax error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected load error")
+	}
+
+	if v.Version() != 2 {
+		t.Errorf("expected version 2 to stay, got %d", v.Version())
+	}
+
+	if err = stop(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+
+	// Changes made while nothing watches are loaded once watching starts again.
+	if err = os.WriteFile(file, []byte("25"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stop = watch()
+	waitVersion(3)
+
+	if err = stop(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+
+	if percent := fireDiscount(t, v.NewSession()); percent != 25 {
+		t.Errorf("expected discount 25, got %d", percent)
+	}
+}